@@ -3,7 +3,7 @@ package client
 
 import (
 	"github.com/hashicorp/consul/api"
-	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/client/kvstore"
 	"github.com/iconnor-code/cogo/core"
 )
 
@@ -12,12 +12,9 @@ type Consul struct {
 }
 
 func NewConsul(config core.IConfig) (*Consul, error) {
-	defaultConfig := api.DefaultConfig()
-	defaultConfig.Address = config.GetConsul().Address
-
-	defaultConsul, err := api.NewClient(defaultConfig)
+	defaultConsul, err := kvstore.NewConsul(config.GetConsul())
 	if err != nil {
-		return nil, cerrs.Wrap(err)
+		return nil, err
 	}
 	return &Consul{
 		defaultClient: defaultConsul,
@@ -1,7 +1,7 @@
 package client
 
 import (
-	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/client/kvstore"
 	"github.com/iconnor-code/cogo/core"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -12,11 +12,9 @@ type EtcdClient struct {
 }
 
 func NewEtcdClient(config core.IConfig) (*EtcdClient, error) {
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints: config.GetEtcd().Endpoints,
-	})
+	client, err := kvstore.NewEtcd(config.GetEtcd())
 	if err != nil {
-		return nil, cerrs.Wrap(err)
+		return nil, err
 	}
 	return &EtcdClient{
 		Client: client,
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/iconnor-code/cogo/core"
-	"go.uber.org/zap"
 	"gorm.io/gorm/logger"
 )
 
@@ -55,13 +54,13 @@ func (l *GormZapLogger) Trace(ctx context.Context, begin time.Time, fc func() (s
 
 	// 将 SQL 查询信息记录到日志中
 	fields := []any{
-		zap.Duration("elapsed", elapsed),
-		zap.String("sql", sql),
-		zap.Int64("rows", rows),
+		"elapsed", elapsed,
+		"sql", sql,
+		"rows", rows,
 	}
 
 	if err != nil {
-		fields = append(fields, zap.Error(err))
+		fields = append(fields, "error", err)
 		l.logger.Error("gorm-trace", fields...)
 		return
 	}
@@ -0,0 +1,37 @@
+// Package kvstore builds the Consul and etcd API clients from their config
+// sections. It depends only on the SDKs and core, so low-level packages
+// such as core/impl/config connect the same way as package client without
+// pulling in its database and Redis drivers.
+package kvstore
+
+import (
+	"github.com/hashicorp/consul/api"
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// NewConsul returns a Consul client for conf. An empty address keeps the
+// SDK default, CONSUL_HTTP_ADDR or 127.0.0.1:8500.
+func NewConsul(conf core.ConsulConfig) (*api.Client, error) {
+	defaultConfig := api.DefaultConfig()
+	if conf.Address != "" {
+		defaultConfig.Address = conf.Address
+	}
+	consul, err := api.NewClient(defaultConfig)
+	if err != nil {
+		return nil, cerrs.Wrap(err)
+	}
+	return consul, nil
+}
+
+// NewEtcd returns an etcd client for conf.
+func NewEtcd(conf core.EtcdConfig) (*clientv3.Client, error) {
+	etcd, err := clientv3.New(clientv3.Config{
+		Endpoints: conf.Endpoints,
+	})
+	if err != nil {
+		return nil, cerrs.Wrap(err)
+	}
+	return etcd, nil
+}
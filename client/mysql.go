@@ -1,8 +1,6 @@
 package client
 
 import (
-	"time"
-
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
 
@@ -53,7 +51,7 @@ func NewMysqlDB(config core.IConfig, logger core.ILogger) (*MysqlDB, error) {
 	maxLifetime := mysqlConf.Pool.MaxLifetime
 	sqlDB.SetMaxOpenConns(maxOpenConns)
 	sqlDB.SetMaxIdleConns(maxIdleConns)
-	sqlDB.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+	sqlDB.SetConnMaxLifetime(maxLifetime.Duration())
 
 	mysqlDB.DB = db
 	return mysqlDB, nil
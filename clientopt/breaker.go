@@ -0,0 +1,295 @@
+package clientopt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultBreakerFailureRatio     = 0.5
+	defaultBreakerSlowCallRatio    = 0.5
+	defaultBreakerMinRequests      = 20
+	defaultBreakerWindow           = 10 * time.Second
+	defaultBreakerOpenDuration     = 30 * time.Second
+	defaultBreakerHalfOpenRequests = 3
+
+	// breakerBuckets is the number of slices the rolling window is kept in.
+	breakerBuckets = 10
+)
+
+// CircuitState is the state of a CircuitBreaker; its value is exported as
+// the grpc_client_circuit_breaker_state gauge.
+type CircuitState int
+
+const (
+	StateClosed CircuitState = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+type breakerBucket struct {
+	start    time.Time
+	total    int
+	failures int
+	slow     int
+}
+
+// CircuitBreaker tracks the outcome of calls to one downstream service and
+// rejects calls with Unavailable while the service looks unhealthy. Its
+// settings are read on every call so they follow config reloads; a
+// disabled breaker lets every call through.
+type CircuitBreaker struct {
+	service string
+	config  func() core.CircuitBreakerConfig
+	logger  core.ILogger
+	metrics *ClientMetrics
+	now     func() time.Time
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	buckets    [breakerBuckets]breakerBucket
+	openedAt   time.Time
+	probes     int
+	successes  int
+}
+
+// NewCircuitBreaker returns a closed breaker for service. logger and
+// metrics may be nil.
+func NewCircuitBreaker(service string, config func() core.CircuitBreakerConfig, logger core.ILogger, metrics *ClientMetrics) *CircuitBreaker {
+	metrics.observeBreakerState(service, StateClosed)
+	return &CircuitBreaker{service: service, config: config, logger: logger, metrics: metrics, now: time.Now}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// CircuitBreakerOption guards unary calls with breaker.
+func CircuitBreakerOption(breaker *CircuitBreaker) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(breaker.unaryInterceptor)
+}
+
+// CircuitBreakerStreamOption guards stream creation with breaker; errors
+// of an established stream are not counted.
+func CircuitBreakerStreamOption(breaker *CircuitBreaker) grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(breaker.streamInterceptor)
+}
+
+func (b *CircuitBreaker) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	conf := b.config()
+	if !conf.Enable {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	generation, err := b.allow(conf)
+	if err != nil {
+		return err
+	}
+	start := b.now()
+	err = invoker(ctx, method, req, reply, cc, opts...)
+	b.record(conf, generation, b.now().Sub(start), err)
+	return err
+}
+
+func (b *CircuitBreaker) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conf := b.config()
+	if !conf.Enable {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	generation, err := b.allow(conf)
+	if err != nil {
+		return nil, err
+	}
+	start := b.now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	b.record(conf, generation, b.now().Sub(start), err)
+	return stream, err
+}
+
+// allow admits a call and returns the generation it was admitted in, so
+// results arriving after a state change are ignored.
+func (b *CircuitBreaker) allow(conf core.CircuitBreakerConfig) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < durationOr(conf.OpenDuration, defaultBreakerOpenDuration) {
+			return 0, b.reject()
+		}
+		b.transition(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.probes >= intOr(conf.HalfOpenRequests, defaultBreakerHalfOpenRequests) {
+			return 0, b.reject()
+		}
+		b.probes++
+	}
+	return b.generation, nil
+}
+
+func (b *CircuitBreaker) reject() error {
+	b.metrics.observeRejected(b.service, "circuit_open")
+	return rejected(codes.Unavailable, "circuit breaker for %s is %s", b.service, b.state)
+}
+
+// rejectedError is returned for calls the breaker or the bulkhead refused
+// without sending them. Callers see its gRPC status; RetryOption, which
+// runs outside both, does not retry it.
+type rejectedError struct {
+	status *status.Status
+}
+
+func rejected(code codes.Code, format string, args ...any) error {
+	return &rejectedError{status: status.Newf(code, format, args...)}
+}
+
+func (e *rejectedError) Error() string { return e.status.Err().Error() }
+
+func (e *rejectedError) GRPCStatus() *status.Status { return e.status }
+
+func (b *CircuitBreaker) record(conf core.CircuitBreakerConfig, generation uint64, elapsed time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	failed, counted := breakerOutcome(err)
+	slow := conf.SlowCallDuration > 0 && elapsed >= conf.SlowCallDuration.Duration()
+
+	if b.state == StateHalfOpen {
+		switch {
+		case !counted:
+			// A probe canceled by its caller proves nothing; free its slot.
+			b.probes--
+		case failed || slow:
+			b.transition(StateOpen)
+		default:
+			b.successes++
+			if b.successes >= intOr(conf.HalfOpenRequests, defaultBreakerHalfOpenRequests) {
+				b.transition(StateClosed)
+			}
+		}
+		return
+	}
+	if !counted {
+		return
+	}
+
+	window := durationOr(conf.Window, defaultBreakerWindow)
+	now := b.now()
+	bucket := b.bucket(now, window)
+	bucket.total++
+	if failed {
+		bucket.failures++
+	}
+	if slow {
+		bucket.slow++
+	}
+
+	var total, failures, slowCalls int
+	for i := range b.buckets {
+		if now.Sub(b.buckets[i].start) < window {
+			total += b.buckets[i].total
+			failures += b.buckets[i].failures
+			slowCalls += b.buckets[i].slow
+		}
+	}
+	if total < intOr(conf.MinRequests, defaultBreakerMinRequests) {
+		return
+	}
+	failureRatio := float64(failures) / float64(total)
+	slowRatio := float64(slowCalls) / float64(total)
+	if failureRatio >= floatOr(conf.FailureRatio, defaultBreakerFailureRatio) ||
+		(conf.SlowCallDuration > 0 && slowRatio >= floatOr(conf.SlowCallRatio, defaultBreakerSlowCallRatio)) {
+		b.transition(StateOpen, "calls", total, "failure_ratio", failureRatio, "slow_call_ratio", slowRatio)
+	}
+}
+
+// bucket returns the slice of the rolling window now falls in, reset if it
+// last held an older slice.
+func (b *CircuitBreaker) bucket(now time.Time, window time.Duration) *breakerBucket {
+	width := max(window/breakerBuckets, time.Millisecond)
+	start := now.Truncate(width)
+	bucket := &b.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+func (b *CircuitBreaker) transition(to CircuitState, fields ...any) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probes, b.successes = 0, 0
+	switch to {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		b.buckets = [breakerBuckets]breakerBucket{}
+	}
+	b.metrics.observeBreakerTransition(b.service, to)
+	if b.logger == nil {
+		return
+	}
+	fields = append([]any{"service", b.service, "from", from.String(), "to", to.String()}, fields...)
+	if to == StateOpen {
+		b.logger.Warn("circuit breaker opened", fields...)
+		return
+	}
+	b.logger.Info("circuit breaker state changed", fields...)
+}
+
+// breakerOutcome reports whether err counts against the downstream service.
+// Errors the caller caused, such as InvalidArgument or its own
+// cancellation, are successes or not counted at all.
+func breakerOutcome(err error) (failed, counted bool) {
+	switch status.Code(err) {
+	case codes.Canceled:
+		return false, false
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.DataLoss:
+		return true, true
+	}
+	return false, true
+}
+
+func durationOr(value core.Duration, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value.Duration()
+	}
+	return fallback
+}
+
+func intOr(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func floatOr(value, fallback float64) float64 {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
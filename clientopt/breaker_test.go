@@ -0,0 +1,130 @@
+package clientopt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestBreaker(conf core.CircuitBreakerConfig) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker := NewCircuitBreaker("order", func() core.CircuitBreakerConfig { return conf }, nil, nil)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+func callWith(breaker *CircuitBreaker, result error, elapsed func()) error {
+	return breaker.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil,
+		func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			if elapsed != nil {
+				elapsed()
+			}
+			return result
+		})
+}
+
+func TestCircuitBreakerOpensOnFailureRatioAndRecovers(t *testing.T) {
+	breaker, clock := newTestBreaker(core.CircuitBreakerConfig{
+		Enable:           true,
+		FailureRatio:     0.5,
+		MinRequests:      4,
+		OpenDuration:     core.Duration(5 * time.Second),
+		HalfOpenRequests: 2,
+	})
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	// Business errors are successes for the breaker.
+	_ = callWith(breaker, nil, nil)
+	_ = callWith(breaker, status.Error(codes.NotFound, "no order"), nil)
+	_ = callWith(breaker, unavailable, nil)
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %v before MinRequests, want closed", breaker.State())
+	}
+	_ = callWith(breaker, unavailable, nil)
+	if breaker.State() != StateOpen {
+		t.Fatalf("state = %v at 2 of 4 failed, want open", breaker.State())
+	}
+
+	called := false
+	err := callWith(breaker, nil, func() { called = true })
+	if called || status.Code(err) != codes.Unavailable {
+		t.Fatalf("called = %v, err = %v; want Unavailable without calling", called, err)
+	}
+
+	clock.now = clock.now.Add(5 * time.Second)
+	if err := callWith(breaker, nil, nil); err != nil {
+		t.Fatalf("first probe: %v", err)
+	}
+	if breaker.State() != StateHalfOpen {
+		t.Fatalf("state = %v after one probe, want half-open", breaker.State())
+	}
+	if err := callWith(breaker, nil, nil); err != nil {
+		t.Fatalf("second probe: %v", err)
+	}
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %v after successful probes, want closed", breaker.State())
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	breaker, clock := newTestBreaker(core.CircuitBreakerConfig{Enable: true, MinRequests: 1})
+	_ = callWith(breaker, status.Error(codes.Internal, "boom"), nil)
+	if breaker.State() != StateOpen {
+		t.Fatalf("state = %v, want open", breaker.State())
+	}
+
+	clock.now = clock.now.Add(defaultBreakerOpenDuration)
+	// A probe canceled by the caller does not decide the state.
+	_ = callWith(breaker, status.Error(codes.Canceled, "canceled"), nil)
+	if breaker.State() != StateHalfOpen {
+		t.Fatalf("state = %v after canceled probe, want half-open", breaker.State())
+	}
+	_ = callWith(breaker, status.Error(codes.DeadlineExceeded, "timeout"), nil)
+	if breaker.State() != StateOpen {
+		t.Fatalf("state = %v after failed probe, want open", breaker.State())
+	}
+}
+
+func TestCircuitBreakerOpensOnSlowCalls(t *testing.T) {
+	breaker, clock := newTestBreaker(core.CircuitBreakerConfig{
+		Enable:           true,
+		MinRequests:      2,
+		SlowCallDuration: core.Duration(time.Second),
+		SlowCallRatio:    1,
+	})
+	slow := func() { clock.now = clock.now.Add(2 * time.Second) }
+
+	_ = callWith(breaker, nil, slow)
+	_ = callWith(breaker, nil, nil)
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %v with one fast call, want closed", breaker.State())
+	}
+	// The window has moved past the calls above.
+	clock.now = clock.now.Add(defaultBreakerWindow)
+	_ = callWith(breaker, nil, slow)
+	_ = callWith(breaker, nil, slow)
+	if breaker.State() != StateOpen {
+		t.Fatalf("state = %v with only slow calls, want open", breaker.State())
+	}
+}
+
+func TestCircuitBreakerDisabledLetsCallsThrough(t *testing.T) {
+	breaker, _ := newTestBreaker(core.CircuitBreakerConfig{MinRequests: 1})
+	for range 3 {
+		if err := callWith(breaker, status.Error(codes.Unavailable, "down"), nil); status.Code(err) != codes.Unavailable {
+			t.Fatalf("err = %v, want the downstream error", err)
+		}
+	}
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %v, want closed", breaker.State())
+	}
+}
@@ -0,0 +1,95 @@
+package clientopt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Bulkhead limits the unary calls in flight to one downstream service.
+// Calls over the limit wait up to MaxWait for a free slot and then fail
+// with ResourceExhausted. The limit is read on every call so it follows
+// config reloads.
+type Bulkhead struct {
+	service string
+	config  func() core.BulkheadConfig
+	metrics *ClientMetrics
+
+	mu       sync.Mutex
+	inFlight int
+	released chan struct{}
+}
+
+// NewBulkhead returns a bulkhead for service. metrics may be nil.
+func NewBulkhead(service string, config func() core.BulkheadConfig, metrics *ClientMetrics) *Bulkhead {
+	return &Bulkhead{service: service, config: config, metrics: metrics, released: make(chan struct{})}
+}
+
+// BulkheadOption limits unary calls with bulkhead. Streams are not limited:
+// they are long-lived and would hold a slot for their whole life.
+func BulkheadOption(bulkhead *Bulkhead) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(bulkhead.unaryInterceptor)
+}
+
+func (b *Bulkhead) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	conf := b.config()
+	if conf.MaxConcurrent <= 0 {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if err := b.acquire(ctx, conf); err != nil {
+		return err
+	}
+	defer b.release()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (b *Bulkhead) acquire(ctx context.Context, conf core.BulkheadConfig) error {
+	var timeout <-chan time.Time
+	if conf.MaxWait > 0 {
+		timer := time.NewTimer(conf.MaxWait.Duration())
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		b.mu.Lock()
+		if b.inFlight < conf.MaxConcurrent {
+			b.inFlight++
+			b.metrics.observeBulkheadInFlight(b.service, b.inFlight)
+			b.mu.Unlock()
+			return nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		if timeout == nil {
+			return b.reject()
+		}
+		select {
+		case <-released:
+		case <-timeout:
+			return b.reject()
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func (b *Bulkhead) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight--
+	b.metrics.observeBulkheadInFlight(b.service, b.inFlight)
+	// Wake every waiter; those that lose the race wait for the next slot.
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+func (b *Bulkhead) reject() error {
+	b.metrics.observeRejected(b.service, "bulkhead_full")
+	return rejected(codes.ResourceExhausted, "bulkhead for %s is full", b.service)
+}
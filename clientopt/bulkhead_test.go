@@ -0,0 +1,59 @@
+package clientopt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBulkheadRejectsCallsOverLimit(t *testing.T) {
+	conf := core.BulkheadConfig{MaxConcurrent: 1}
+	bulkhead := NewBulkhead("order", func() core.BulkheadConfig { return conf }, nil)
+
+	started, finish := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- bulkhead.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				close(started)
+				<-finish
+				return nil
+			})
+	}()
+	<-started
+
+	noop := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error { return nil }
+	if err := bulkhead.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, noop); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err = %v, want ResourceExhausted", err)
+	}
+
+	// A waiting call gets the slot once the first call finishes.
+	conf.MaxWait = core.Duration(time.Second)
+	time.AfterFunc(20*time.Millisecond, func() { close(finish) })
+	if err := bulkhead.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, noop); err != nil {
+		t.Fatalf("waiting call: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkheadWaitFollowsCallerContext(t *testing.T) {
+	conf := core.BulkheadConfig{MaxConcurrent: 1, MaxWait: core.Duration(time.Minute)}
+	bulkhead := NewBulkhead("order", func() core.BulkheadConfig { return conf }, nil)
+	if err := bulkhead.acquire(context.Background(), conf); err != nil {
+		t.Fatal(err)
+	}
+	defer bulkhead.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bulkhead.acquire(ctx, conf); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,86 @@
+package clientopt
+
+import (
+	"context"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineOption shortens the deadline of outgoing unary calls by the
+// margin returned by margin, leaving the caller time to handle the result
+// before its own deadline. Calls with no budget left fail with
+// DeadlineExceeded without reaching the network. margin is read per call
+// so it can follow config reloads.
+func DeadlineOption(margin func() time.Duration) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(deadlineInterceptor(margin))
+}
+
+// DeadlineStreamOption is DeadlineOption for streams. The shortened context
+// is released once RecvMsg reports the end of the stream.
+func DeadlineStreamOption(margin func() time.Duration) grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(deadlineStreamInterceptor(margin))
+}
+
+func deadlineInterceptor(margin func() time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := withDeadlineMargin(ctx, method, margin())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func deadlineStreamInterceptor(margin func() time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel, err := withDeadlineMargin(ctx, method, margin())
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &deadlineClientStream{ClientStream: stream, cancel: cancel}, nil
+	}
+}
+
+// withDeadlineMargin shortens the deadline of ctx by margin. Calls without
+// a deadline are left alone; calls with no budget left are rejected.
+func withDeadlineMargin(ctx context.Context, method string, margin time.Duration) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	remaining := time.Until(deadline) - margin
+	if remaining <= 0 {
+		if srvCtx, ok := core.SrvCtxFromContext(ctx); ok {
+			srvCtx.Logger().Warn("deadline budget exhausted, skipping downstream call",
+				"downstream_method", method,
+				"remaining", time.Until(deadline),
+			)
+		}
+		return nil, nil, status.Errorf(codes.DeadlineExceeded, "deadline budget exhausted before calling %s", method)
+	}
+	ctx, cancel := context.WithTimeout(ctx, remaining)
+	return ctx, cancel, nil
+}
+
+type deadlineClientStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *deadlineClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package clientopt
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeadlineInterceptorKeepsMarginFromRemainingBudget(t *testing.T) {
+	interceptor := deadlineInterceptor(func() time.Duration { return 100 * time.Millisecond })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	parent, _ := ctx.Deadline()
+
+	var got time.Time
+	err := interceptor(ctx, "/order.OrderService/Get", nil, nil, nil, func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		got, _ = ctx.Deadline()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shortened := parent.Sub(got); shortened < 90*time.Millisecond || shortened > 110*time.Millisecond {
+		t.Fatalf("downstream deadline is %v before the caller's, want about 100ms", shortened)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	called := false
+	err = interceptor(ctx, "/order.OrderService/Get", nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		called = true
+		return nil
+	})
+	if called || status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("called = %v, err = %v; want DeadlineExceeded without calling", called, err)
+	}
+}
+
+func TestDeadlineStreamInterceptorKeepsMarginFromRemainingBudget(t *testing.T) {
+	interceptor := deadlineStreamInterceptor(func() time.Duration { return 100 * time.Millisecond })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	parent, _ := ctx.Deadline()
+
+	var streamCtx context.Context
+	stream, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/order.OrderService/Watch", func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		streamCtx = ctx
+		return &eofClientStream{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := streamCtx.Deadline()
+	if shortened := parent.Sub(got); shortened < 90*time.Millisecond || shortened > 110*time.Millisecond {
+		t.Fatalf("stream deadline is %v before the caller's, want about 100ms", shortened)
+	}
+	if streamCtx.Err() != nil {
+		t.Fatalf("stream context ended before the stream: %v", streamCtx.Err())
+	}
+	if err := stream.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("recv err = %v, want io.EOF", err)
+	}
+	if streamCtx.Err() == nil {
+		t.Fatal("stream context is still live after the stream ended")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	called := false
+	_, err = interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/order.OrderService/Watch", func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		called = true
+		return &eofClientStream{}, nil
+	})
+	if called || status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("called = %v, err = %v; want DeadlineExceeded without opening the stream", called, err)
+	}
+}
+
+type eofClientStream struct {
+	grpc.ClientStream
+}
+
+func (*eofClientStream) RecvMsg(any) error { return io.EOF }
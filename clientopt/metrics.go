@@ -0,0 +1,168 @@
+package clientopt
+
+import (
+	"context"
+	"time"
+
+	"github.com/iconnor-code/cogo/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ClientMetrics holds the per-method counter and latency histogram recorded
+// for outgoing calls, labelled by logical downstream service, and the state
+// of their circuit breakers and bulkheads. Its methods accept a nil
+// receiver.
+type ClientMetrics struct {
+	handled            *prometheus.CounterVec
+	duration           *prometheus.HistogramVec
+	breakerState       *prometheus.GaugeVec
+	breakerTransitions *prometheus.CounterVec
+	bulkheadInFlight   *prometheus.GaugeVec
+	rejected           *prometheus.CounterVec
+	retries            *prometheus.CounterVec
+}
+
+// NewClientMetrics registers the client metrics under namespace, usually
+// MetricsConfig.Prefix. A nil registerer uses the default Prometheus registry.
+func NewClientMetrics(namespace string, registerer prometheus.Registerer) (*ClientMetrics, error) {
+	labels := []string{"service", "method", "code"}
+	handled, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handled_total",
+		Help:      "Total number of RPCs completed by the client, regardless of success or failure.",
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := utils.RegisterCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_handling_seconds",
+		Help:      "Latency of RPCs issued by the client.",
+		Buckets:   prometheus.DefBuckets,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	breakerState, err := utils.RegisterCollector(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_circuit_breaker_state",
+		Help:      "State of the circuit breaker per service: 0 closed, 1 half-open, 2 open.",
+	}, []string{"service"}))
+	if err != nil {
+		return nil, err
+	}
+	breakerTransitions, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_circuit_breaker_transitions_total",
+		Help:      "Total number of circuit breaker state changes, by the state entered.",
+	}, []string{"service", "state"}))
+	if err != nil {
+		return nil, err
+	}
+	bulkheadInFlight, err := utils.RegisterCollector(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_bulkhead_in_flight",
+		Help:      "Number of calls holding a bulkhead slot per service.",
+	}, []string{"service"}))
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_rejected_total",
+		Help:      "Total number of calls rejected by the client before reaching the network.",
+	}, []string{"service", "reason"}))
+	if err != nil {
+		return nil, err
+	}
+	retries, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_retries_total",
+		Help:      "Total number of retries of idempotent calls, and of retries skipped because the retry budget was exhausted.",
+	}, []string{"service", "result"}))
+	if err != nil {
+		return nil, err
+	}
+	return &ClientMetrics{
+		handled:            handled,
+		duration:           duration,
+		breakerState:       breakerState,
+		breakerTransitions: breakerTransitions,
+		bulkheadInFlight:   bulkheadInFlight,
+		rejected:           rejected,
+		retries:            retries,
+	}, nil
+}
+
+// MetricsOption records unary calls and streams made on a connection to
+// service. Stream latency covers the time until the stream is established.
+func MetricsOption(metrics *ClientMetrics, service string) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			start := time.Now()
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			metrics.observe(service, method, time.Since(start), err)
+			return err
+		},
+	)
+}
+
+// MetricsStreamOption is the streaming counterpart of MetricsOption.
+func MetricsStreamOption(metrics *ClientMetrics, service string) grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			start := time.Now()
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			metrics.observe(service, method, time.Since(start), err)
+			return stream, err
+		},
+	)
+}
+
+func (m *ClientMetrics) observe(service, method string, duration time.Duration, err error) {
+	labels := prometheus.Labels{
+		"service": service,
+		"method":  method,
+		"code":    status.Code(err).String(),
+	}
+	m.handled.With(labels).Inc()
+	m.duration.With(labels).Observe(duration.Seconds())
+}
+
+func (m *ClientMetrics) observeBreakerState(service string, state CircuitState) {
+	if m == nil {
+		return
+	}
+	m.breakerState.WithLabelValues(service).Set(float64(state))
+}
+
+func (m *ClientMetrics) observeBreakerTransition(service string, state CircuitState) {
+	if m == nil {
+		return
+	}
+	m.breakerState.WithLabelValues(service).Set(float64(state))
+	m.breakerTransitions.WithLabelValues(service, state.String()).Inc()
+}
+
+func (m *ClientMetrics) observeBulkheadInFlight(service string, inFlight int) {
+	if m == nil {
+		return
+	}
+	m.bulkheadInFlight.WithLabelValues(service).Set(float64(inFlight))
+}
+
+func (m *ClientMetrics) observeRejected(service, reason string) {
+	if m == nil {
+		return
+	}
+	m.rejected.WithLabelValues(service, reason).Inc()
+}
+
+func (m *ClientMetrics) observeRetry(service, result string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(service, result).Inc()
+}
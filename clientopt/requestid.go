@@ -0,0 +1,45 @@
+package clientopt
+
+import (
+	"context"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDOption forwards the request ID of the request being served on
+// outgoing unary calls, so downstream services log the same ID.
+func RequestIDOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ContextWithRequestID(ctx), method, req, reply, cc, opts...)
+		},
+	)
+}
+
+func RequestIDStreamOption() grpc.DialOption {
+	return grpc.WithChainStreamInterceptor(
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ContextWithRequestID(ctx), desc, cc, method, opts...)
+		},
+	)
+}
+
+// ContextWithRequestID adds the request ID stored on the ISrvCtx of ctx to
+// the outgoing metadata, unless the call already sets one.
+func ContextWithRequestID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(core.RequestIDHeader)) > 0 {
+		return ctx
+	}
+	srvCtx, ok := core.SrvCtxFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	value, _ := srvCtx.GetField(core.RequestIDKey)
+	id, _ := value.(string)
+	if id == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, core.RequestIDHeader, id)
+}
@@ -0,0 +1,31 @@
+package clientopt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestContextWithRequestIDForwardsServedRequestID(t *testing.T) {
+	srvCtx := srvctx.NewSrvCtx(nil)
+	srvCtx.SetField(core.RequestIDKey, "req-1")
+	ctx := context.WithValue(context.Background(), core.SrvCtx, srvCtx)
+
+	md, _ := metadata.FromOutgoingContext(ContextWithRequestID(ctx))
+	if got := md.Get(core.RequestIDHeader); len(got) != 1 || got[0] != "req-1" {
+		t.Fatalf("x-request-id metadata = %v", got)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, core.RequestIDHeader, "explicit")
+	md, _ = metadata.FromOutgoingContext(ContextWithRequestID(ctx))
+	if got := md.Get(core.RequestIDHeader); len(got) != 1 || got[0] != "explicit" {
+		t.Fatalf("x-request-id metadata = %v, want the caller's value kept", got)
+	}
+
+	if ctx := ContextWithRequestID(context.Background()); ctx != context.Background() {
+		t.Fatal("ContextWithRequestID changed a context without srvctx")
+	}
+}
@@ -0,0 +1,170 @@
+package clientopt
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RetryBudget is the token bucket of retryThrottling in the gRPC service
+// config, applied to the retries of RetryOption: failed calls take a token,
+// successful ones return a fraction of one, and retries stop while half the
+// tokens or fewer are left.
+type RetryBudget struct {
+	mu      sync.Mutex
+	tokens  float64
+	started bool
+}
+
+func NewRetryBudget() *RetryBudget {
+	return &RetryBudget{}
+}
+
+func (b *RetryBudget) onSuccess(conf core.RetryBudgetConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	maxTokens := b.maxTokens(conf)
+	b.tokens = math.Min(maxTokens, b.tokens+floatOr(conf.TokenRatio, defaultRetryBudgetTokenRatio))
+}
+
+// onFailure takes a token and reports whether a retry may be sent.
+func (b *RetryBudget) onFailure(conf core.RetryBudgetConfig) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	maxTokens := b.maxTokens(conf)
+	b.tokens = math.Max(0, b.tokens-1)
+	return b.tokens > maxTokens/2
+}
+
+// maxTokens returns the configured size and fills the bucket on first use.
+// A size lowered by a config reload caps the tokens left.
+func (b *RetryBudget) maxTokens(conf core.RetryBudgetConfig) float64 {
+	maxTokens := float64(intOr(conf.MaxTokens, defaultRetryBudgetMaxTokens))
+	if !b.started {
+		b.tokens, b.started = maxTokens, true
+	}
+	b.tokens = math.Min(b.tokens, maxTokens)
+	return maxTokens
+}
+
+// descriptorResolver finds method descriptors; protoregistry.GlobalFiles
+// holds every generated proto package linked into the binary.
+type descriptorResolver interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+type retrier struct {
+	service string
+	policy  func() core.ServicePolicyConfig
+	budget  *RetryBudget
+	metrics *ClientMetrics
+	files   descriptorResolver
+}
+
+// RetryOption retries unary calls to service when discovery.policies sets
+// retry.idempotent, for methods whose proto definition declares them
+// idempotent. Methods covered by the retry or hedging policy of the service
+// config are left to gRPC. budget is shared by the connections of one
+// service and metrics may be nil. The policy is read on every call so it
+// follows config reloads. Calls refused by the circuit breaker or the
+// bulkhead are returned without a retry: retrying them would only add load
+// while the downstream service is already protected.
+func RetryOption(service string, policy func() core.ServicePolicyConfig, budget *RetryBudget, metrics *ClientMetrics) grpc.DialOption {
+	r := &retrier{service: service, policy: policy, budget: budget, metrics: metrics, files: protoregistry.GlobalFiles}
+	return grpc.WithChainUnaryInterceptor(r.unaryInterceptor)
+}
+
+func (r *retrier) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	policy := r.policy()
+	conf := policy.Retry
+	if conf.MaxAttempts < 2 || !conf.Idempotent ||
+		policyMethodMatches(conf.Methods, method) || policyMethodMatches(policy.Hedging.Methods, method) ||
+		!r.idempotent(method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	retryable := retryableCodes(conf.Codes)
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			r.budget.onSuccess(policy.RetryBudget)
+			return nil
+		}
+		var rejectedErr *rejectedError
+		if errors.As(err, &rejectedErr) || !retryable[status.Code(err)] {
+			return err
+		}
+		allowed := r.budget.onFailure(policy.RetryBudget)
+		if attempt >= conf.MaxAttempts {
+			return err
+		}
+		if !allowed {
+			r.metrics.observeRetry(r.service, "budget_exhausted")
+			return err
+		}
+		timer := time.NewTimer(retryBackoff(conf, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		r.metrics.observeRetry(r.service, "retried")
+	}
+}
+
+// idempotent reports whether method sets idempotency_level to IDEMPOTENT
+// or NO_SIDE_EFFECTS in its proto definition.
+func (r *retrier) idempotent(method string) bool {
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", "."))
+	descriptor, err := r.files.FindDescriptorByName(name)
+	if err != nil {
+		return false
+	}
+	methodDescriptor, ok := descriptor.(protoreflect.MethodDescriptor)
+	if !ok {
+		return false
+	}
+	options, ok := methodDescriptor.Options().(*descriptorpb.MethodOptions)
+	if !ok {
+		return false
+	}
+	switch options.GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_IDEMPOTENT, descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return true
+	}
+	return false
+}
+
+// retryBackoff returns the wait before retry n, random up to the
+// exponential backoff as gRPC computes it.
+func retryBackoff(conf core.RetryPolicyConfig, n int) time.Duration {
+	backoff := float64(durationOr(conf.InitialBackoff, defaultRetryInitialBackoff)) *
+		math.Pow(floatOr(conf.BackoffMultiplier, defaultRetryBackoffMultiplier), float64(n-1))
+	backoff = math.Min(backoff, float64(durationOr(conf.MaxBackoff, defaultRetryMaxBackoff)))
+	return time.Duration(rand.Float64() * backoff)
+}
+
+func retryableCodes(names []string) map[codes.Code]bool {
+	retryable := make(map[codes.Code]bool, len(names))
+	for _, name := range codeNames(names) {
+		var code codes.Code
+		// Names are checked when the config is loaded.
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err == nil {
+			retryable[code] = true
+		}
+	}
+	return retryable
+}
@@ -0,0 +1,171 @@
+package clientopt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func orderFiles(t *testing.T) *protoregistry.Files {
+	t.Helper()
+	method := func(name string, level descriptorpb.MethodOptions_IdempotencyLevel) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".order.Empty"),
+			OutputType: proto.String(".order.Empty"),
+			Options:    &descriptorpb.MethodOptions{IdempotencyLevel: level.Enum()},
+		}
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("order.proto"),
+		Package:     proto.String("order"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Empty")}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("OrderService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("Get", descriptorpb.MethodOptions_NO_SIDE_EFFECTS),
+				method("Create", descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN),
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(file); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func newTestRetrier(t *testing.T, policy core.ServicePolicyConfig) *retrier {
+	return &retrier{
+		service: "order",
+		policy:  func() core.ServicePolicyConfig { return policy },
+		budget:  NewRetryBudget(),
+		files:   orderFiles(t),
+	}
+}
+
+// failing returns an invoker that fails with code the first n calls and
+// counts all calls.
+func failing(code codes.Code, n int, calls *int) grpc.UnaryInvoker {
+	return func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		*calls++
+		if *calls <= n {
+			return status.Error(code, "failed")
+		}
+		return nil
+	}
+}
+
+func TestRetryInterceptorRetriesIdempotentMethods(t *testing.T) {
+	r := newTestRetrier(t, core.ServicePolicyConfig{Retry: core.RetryPolicyConfig{
+		MaxAttempts:    3,
+		InitialBackoff: core.Duration(1),
+		Idempotent:     true,
+	}})
+
+	calls := 0
+	if err := r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 2, &calls)); err != nil {
+		t.Fatalf("err = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	err := r.unaryInterceptor(context.Background(), "/order.OrderService/Create", nil, nil, nil, failing(codes.Unavailable, 2, &calls))
+	if calls != 1 || status.Code(err) != codes.Unavailable {
+		t.Fatalf("calls = %d, err = %v; a method not declared idempotent must not be retried", calls, err)
+	}
+
+	calls = 0
+	err = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.InvalidArgument, 2, &calls))
+	if calls != 1 || status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("calls = %d, err = %v; non-retryable codes must not be retried", calls, err)
+	}
+}
+
+func TestRetryInterceptorLeavesServiceConfigMethodsToGRPC(t *testing.T) {
+	r := newTestRetrier(t, core.ServicePolicyConfig{Retry: core.RetryPolicyConfig{
+		MaxAttempts: 3,
+		Idempotent:  true,
+		Methods:     []string{"order.OrderService"},
+	}})
+	calls := 0
+	_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 2, &calls))
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1: gRPC retries methods in the service config", calls)
+	}
+}
+
+func TestRetryBudgetStopsRetriesDuringOutage(t *testing.T) {
+	r := newTestRetrier(t, core.ServicePolicyConfig{
+		Retry:       core.RetryPolicyConfig{MaxAttempts: 5, InitialBackoff: core.Duration(1), Idempotent: true},
+		RetryBudget: core.RetryBudgetConfig{MaxTokens: 4},
+	})
+
+	calls := 0
+	_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 100, &calls))
+	// Tokens go 4 -> 3 (retry) -> 2 (at half, stop).
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2 before the budget runs out", calls)
+	}
+	calls = 0
+	_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 100, &calls))
+	if calls != 1 {
+		t.Fatalf("calls = %d, want no retries with an exhausted budget", calls)
+	}
+
+	// Successes refill the budget a tenth of a token at a time.
+	for range 30 {
+		calls = 0
+		_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 0, &calls))
+	}
+	calls = 0
+	_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 1, &calls))
+	if calls != 2 {
+		t.Fatalf("calls = %d, want a retry once the budget has refilled", calls)
+	}
+}
+
+func TestRetryInterceptorDoesNotRetryRejectedCalls(t *testing.T) {
+	r := newTestRetrier(t, core.ServicePolicyConfig{
+		Retry:       core.RetryPolicyConfig{MaxAttempts: 5, InitialBackoff: core.Duration(1), Idempotent: true},
+		RetryBudget: core.RetryBudgetConfig{MaxTokens: 4},
+	})
+	breaker, _ := newTestBreaker(core.CircuitBreakerConfig{Enable: true, MinRequests: 1})
+	_ = callWith(breaker, status.Error(codes.Unavailable, "connection refused"), nil)
+	bulkhead := NewBulkhead("order", func() core.BulkheadConfig { return core.BulkheadConfig{MaxConcurrent: 1} }, nil)
+	bulkhead.inFlight = 1
+
+	for name, guard := range map[string]grpc.UnaryClientInterceptor{
+		"open breaker":  breaker.unaryInterceptor,
+		"full bulkhead": bulkhead.unaryInterceptor,
+	} {
+		calls := 0
+		invoker := failing(codes.Unavailable, 100, &calls)
+		attempts := 0
+		err := r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				attempts++
+				return guard(ctx, method, req, reply, cc, invoker, opts...)
+			})
+		if attempts != 1 || calls != 0 || status.Code(err) == codes.OK {
+			t.Fatalf("%s: attempts = %d, calls = %d, err = %v; want one rejected attempt", name, attempts, calls, err)
+		}
+	}
+	// Rejected calls leave the budget alone.
+	calls := 0
+	_ = r.unaryInterceptor(context.Background(), "/order.OrderService/Get", nil, nil, nil, failing(codes.Unavailable, 1, &calls))
+	if calls != 2 {
+		t.Fatalf("calls = %d, want a retry with the budget untouched", calls)
+	}
+}
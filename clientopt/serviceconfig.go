@@ -0,0 +1,140 @@
+package clientopt
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+)
+
+const (
+	defaultRetryInitialBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff        = time.Second
+	defaultRetryBackoffMultiplier = 2
+	defaultRetryBudgetMaxTokens   = 10
+	defaultRetryBudgetTokenRatio  = 0.1
+)
+
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	RetryThrottling     *retryThrottling      `json:"retryThrottling,omitempty"`
+}
+
+type methodConfig struct {
+	Name          []methodName   `json:"name"`
+	RetryPolicy   *retryPolicy   `json:"retryPolicy,omitempty"`
+	HedgingPolicy *hedgingPolicy `json:"hedgingPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service,omitempty"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type hedgingPolicy struct {
+	MaxAttempts         int      `json:"maxAttempts"`
+	HedgingDelay        string   `json:"hedgingDelay,omitempty"`
+	NonFatalStatusCodes []string `json:"nonFatalStatusCodes,omitempty"`
+}
+
+type retryThrottling struct {
+	MaxTokens  int     `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+// ServiceConfig returns the gRPC service config JSON of a connection:
+// round-robin load balancing plus the retry and hedging policies of
+// policy, throttled by its retry budget.
+func ServiceConfig(policy core.ServicePolicyConfig) string {
+	config := serviceConfig{LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}}}
+	if retry := policy.Retry; retry.MaxAttempts >= 2 && len(retry.Methods) > 0 {
+		config.MethodConfig = append(config.MethodConfig, methodConfig{
+			Name: methodNames(retry.Methods),
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          retry.MaxAttempts,
+				InitialBackoff:       serviceConfigDuration(durationOr(retry.InitialBackoff, defaultRetryInitialBackoff)),
+				MaxBackoff:           serviceConfigDuration(durationOr(retry.MaxBackoff, defaultRetryMaxBackoff)),
+				BackoffMultiplier:    floatOr(retry.BackoffMultiplier, defaultRetryBackoffMultiplier),
+				RetryableStatusCodes: codeNames(retry.Codes),
+			},
+		})
+	}
+	if hedging := policy.Hedging; hedging.MaxAttempts >= 2 && len(hedging.Methods) > 0 {
+		hedgingConfig := &hedgingPolicy{MaxAttempts: hedging.MaxAttempts}
+		if hedging.Delay > 0 {
+			hedgingConfig.HedgingDelay = serviceConfigDuration(hedging.Delay.Duration())
+		}
+		for _, name := range hedging.NonFatalCodes {
+			hedgingConfig.NonFatalStatusCodes = append(hedgingConfig.NonFatalStatusCodes, strings.ToUpper(strings.TrimSpace(name)))
+		}
+		config.MethodConfig = append(config.MethodConfig, methodConfig{
+			Name:          methodNames(hedging.Methods),
+			HedgingPolicy: hedgingConfig,
+		})
+	}
+	if len(config.MethodConfig) > 0 {
+		config.RetryThrottling = &retryThrottling{
+			MaxTokens:  intOr(policy.RetryBudget.MaxTokens, defaultRetryBudgetMaxTokens),
+			TokenRatio: floatOr(policy.RetryBudget.TokenRatio, defaultRetryBudgetTokenRatio),
+		}
+	}
+	// Only plain strings and numbers: marshaling cannot fail.
+	data, _ := json.Marshal(config)
+	return string(data)
+}
+
+// methodNames converts "*", "pkg.Service" and "pkg.Service/Method" to
+// service config names.
+func methodNames(methods []string) []methodName {
+	names := make([]methodName, 0, len(methods))
+	for _, method := range methods {
+		method = strings.TrimSpace(method)
+		if method == "*" {
+			names = append(names, methodName{})
+			continue
+		}
+		service, name, _ := strings.Cut(method, "/")
+		names = append(names, methodName{Service: service, Method: name})
+	}
+	return names
+}
+
+// policyMethodMatches reports whether the full method name, as passed to
+// interceptors, is covered by one of methods.
+func policyMethodMatches(methods []string, fullMethod string) bool {
+	service, name, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	for _, method := range methods {
+		method = strings.TrimSpace(method)
+		if method == "*" || method == service || method == service+"/"+name {
+			return true
+		}
+	}
+	return false
+}
+
+func codeNames(names []string) []string {
+	if len(names) == 0 {
+		return []string{"UNAVAILABLE"}
+	}
+	upper := make([]string, 0, len(names))
+	for _, name := range names {
+		upper = append(upper, strings.ToUpper(strings.TrimSpace(name)))
+	}
+	return upper
+}
+
+// serviceConfigDuration formats d as the service config expects, e.g. "0.1s".
+func serviceConfigDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,63 @@
+package clientopt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestServiceConfigIncludesRetryHedgingAndBudget(t *testing.T) {
+	config := ServiceConfig(core.ServicePolicyConfig{
+		Retry: core.RetryPolicyConfig{
+			MaxAttempts: 3,
+			Codes:       []string{"unavailable", "RESOURCE_EXHAUSTED"},
+			Methods:     []string{"order.OrderService/Get", "order.QueryService"},
+		},
+		Hedging: core.HedgingPolicyConfig{
+			Methods:     []string{"order.OrderService/List"},
+			MaxAttempts: 2,
+			Delay:       core.Duration(50 * time.Millisecond),
+		},
+	})
+
+	var got serviceConfig
+	if err := json.Unmarshal([]byte(config), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.MethodConfig) != 2 || got.RetryThrottling == nil {
+		t.Fatalf("service config = %s, want retry and hedging method configs with throttling", config)
+	}
+	retry := got.MethodConfig[0]
+	if retry.Name[0] != (methodName{Service: "order.OrderService", Method: "Get"}) || retry.Name[1] != (methodName{Service: "order.QueryService"}) {
+		t.Fatalf("retry names = %+v", retry.Name)
+	}
+	if retry.RetryPolicy.InitialBackoff != "0.1s" || retry.RetryPolicy.RetryableStatusCodes[0] != "UNAVAILABLE" {
+		t.Fatalf("retry policy = %+v", retry.RetryPolicy)
+	}
+	if hedging := got.MethodConfig[1].HedgingPolicy; hedging == nil || hedging.HedgingDelay != "0.05s" {
+		t.Fatalf("hedging policy = %+v", hedging)
+	}
+	if *got.RetryThrottling != (retryThrottling{MaxTokens: 10, TokenRatio: 0.1}) {
+		t.Fatalf("retry throttling = %+v", got.RetryThrottling)
+	}
+
+	// grpc.NewClient rejects service configs it cannot parse.
+	conn, err := grpc.NewClient("passthrough:///order",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(config),
+	)
+	if err != nil {
+		t.Fatalf("service config rejected: %v\n%s", err, config)
+	}
+	_ = conn.Close()
+}
+
+func TestServiceConfigWithoutPoliciesOnlyBalances(t *testing.T) {
+	if got := ServiceConfig(core.ServicePolicyConfig{}); got != `{"loadBalancingConfig":[{"round_robin":{}}]}` {
+		t.Fatalf("service config = %s", got)
+	}
+}
@@ -1,6 +1,12 @@
 // Package core core interface
 package core
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 type IConfig interface {
 	GetMode() string
 	GetBizID() int
@@ -18,9 +24,21 @@ type IConfig interface {
 	GetSMTP() SMTPConfig
 	GetJWT() JWTConfig
 	GetOSS() OSSConfig
+	GetTracing() TracingConfig
+	GetTLS() TLSConfig
 	Reload() error
 }
 
+// IConfigWatcher is implemented by configs that reload at runtime. fn runs
+// after a reload changed the named top-level section, using its mapstructure
+// key such as "logger" or "discovery"; an empty section matches any change.
+type IConfigWatcher interface {
+	Subscribe(section string, fn func(old, new Config))
+}
+
+// Config holds every framework section. Fields tagged secret:"true" hold
+// credentials; they may be given as file://, env:// or consul-kv://
+// references and are never printed by config dumps or validation errors.
 type Config struct {
 	Mode    string `mapstructure:"mode" yaml:"mode"`
 	BizID   int    `mapstructure:"biz_id" yaml:"biz_id"`
@@ -39,11 +57,80 @@ type Config struct {
 	SMTP      SMTPConfig      `mapstructure:"smtp" yaml:"smtp"`
 	JWT       JWTConfig       `mapstructure:"jwt" yaml:"jwt"`
 	OSS       OSSConfig       `mapstructure:"oss" yaml:"oss"`
+	Tracing   TracingConfig   `mapstructure:"tracing" yaml:"tracing"`
+	TLS       TLSConfig       `mapstructure:"tls" yaml:"tls"`
 }
 
 type GRPCConfig struct {
-	Listen          string `mapstructure:"listen" yaml:"listen"`
-	GatewayEndpoint string `mapstructure:"gateway_endpoint" yaml:"gateway_endpoint"`
+	Listen          string               `mapstructure:"listen" yaml:"listen"`
+	GatewayEndpoint string               `mapstructure:"gateway_endpoint" yaml:"gateway_endpoint"`
+	PayloadLog      GRPCPayloadLogConfig `mapstructure:"payload_log" yaml:"payload_log"`
+	RateLimit       GRPCRateLimitConfig  `mapstructure:"rate_limit" yaml:"rate_limit"`
+}
+
+// GRPCRateLimitConfig lists the token buckets of RateLimitInterceptor. A
+// request must pass every rule matching its method.
+type GRPCRateLimitConfig struct {
+	Rules []GRPCRateLimitRule `mapstructure:"rules" yaml:"rules"`
+	// TrustForwardedFor keys "ip" rules on the last x-forwarded-for entry,
+	// which the gateway sets to its HTTP client, instead of the gRPC peer.
+	// Enable it only when callers cannot reach the gRPC port directly.
+	TrustForwardedFor bool `mapstructure:"trust_forwarded_for" yaml:"trust_forwarded_for"`
+}
+
+type GRPCRateLimitRule struct {
+	// Method is a full method name or a prefix ending in "*"; empty matches
+	// every method.
+	Method string `mapstructure:"method" yaml:"method"`
+	// Key selects the bucket: one per method, user ID, caller biz name or
+	// client IP. Requests without the key, such as anonymous ones for
+	// "user", skip the rule.
+	Key string `mapstructure:"key" yaml:"key" validate:"required,oneof=method user biz ip"`
+	// Rate is the number of requests per second refilled into each bucket.
+	Rate float64 `mapstructure:"rate" yaml:"rate" validate:"required,min=0"`
+	// Burst is the bucket size; 0 means Rate rounded up.
+	Burst int `mapstructure:"burst" yaml:"burst" validate:"min=0"`
+}
+
+// GRPCPayloadLogConfig makes RequestLogInterceptor log protojson-encoded
+// request and response bodies. It is off unless Methods or SampleRatio is
+// set.
+type GRPCPayloadLogConfig struct {
+	// Methods always log payloads: full methods such as
+	// "/account.AuthService/Login", or "/account.AuthService/*".
+	Methods []string `mapstructure:"methods" yaml:"methods"`
+	// SampleRatio logs the payloads of this share of the other requests.
+	SampleRatio float64 `mapstructure:"sample_ratio" yaml:"sample_ratio" validate:"min=0,max=1"`
+	// MaxBytes truncates each encoded payload; 0 means 4096.
+	MaxBytes int `mapstructure:"max_bytes" yaml:"max_bytes" validate:"min=0"`
+	// MaskFields are field paths masked in every payload that has them,
+	// such as "password" or "profile.phone".
+	MaskFields []string `mapstructure:"mask_fields" yaml:"mask_fields"`
+}
+
+// TLSConfig secures the gRPC server, the gateway's connection to it and
+// rpcclient.Pool connections. Certificate files are read again when they
+// change on disk, so rotated certificates apply to new connections without
+// a restart.
+type TLSConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// CAFile verifies the certificates of servers and, unless ClientAuth
+	// is none or request, of clients; empty uses the system roots.
+	CAFile string `mapstructure:"ca_file" yaml:"ca_file"`
+	// CertFile and KeyFile are the certificate of the gRPC server, also
+	// presented as client certificate to servers that ask for one.
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file" validate:"required_if=enable true"`
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file" validate:"required_if=enable true"`
+	// ClientAuth is the client certificate policy of the gRPC server;
+	// empty means none.
+	ClientAuth string `mapstructure:"client_auth" yaml:"client_auth" validate:"oneof=none request require verify_if_given require_and_verify"`
+	// ServerName is the name expected in server certificates; empty uses
+	// the host of the dialed target. Set it when the gateway dials its
+	// gRPC server on a loopback address.
+	ServerName string `mapstructure:"server_name" yaml:"server_name"`
+	// ReloadInterval is how often certificate files are checked for
+	// changes; empty means 30s.
+	ReloadInterval Duration `mapstructure:"reload_interval" yaml:"reload_interval" validate:"duration"`
 }
 
 type HTTPConfig struct {
@@ -52,44 +139,143 @@ type HTTPConfig struct {
 }
 
 type HTTPSSLConfig struct {
-	CertFile string `mapstructure:"cert_file" yaml:"cert_file"`
-	KeyFile  string `mapstructure:"key_file" yaml:"key_file"`
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file" validate:"required_with=key_file"`
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file" validate:"required_with=cert_file"`
 }
 
 type LoggerConfig struct {
-	Level      int    `mapstructure:"level" yaml:"level"`
+	Level      int    `mapstructure:"level" yaml:"level" validate:"min=-1,max=5"`
 	FilePath   string `mapstructure:"file_path" yaml:"file_path"`
-	MaxSize    int    `mapstructure:"max_size" yaml:"max_size"`
-	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups"`
-	MaxAge     int    `mapstructure:"max_age" yaml:"max_age"`
+	MaxSize    int    `mapstructure:"max_size" yaml:"max_size" validate:"min=0"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups" validate:"min=0"`
+	MaxAge     int    `mapstructure:"max_age" yaml:"max_age" validate:"min=0"`
+	// Compress gzips rotated files of FilePath.
+	Compress bool `mapstructure:"compress" yaml:"compress"`
+	// Modules overrides Level per module, e.g. {"rpcclient": -1}.
+	Modules   map[string]int        `mapstructure:"modules" yaml:"modules"`
+	Sampling  LoggerSamplingConfig  `mapstructure:"sampling" yaml:"sampling"`
+	RateLimit LoggerRateLimitConfig `mapstructure:"rate_limit" yaml:"rate_limit"`
+	// Sinks replaces the default outputs (JSON on stdout, plus info.log and
+	// error.log under FilePath) when set.
+	Sinks  []LoggerSinkConfig `mapstructure:"sinks" yaml:"sinks"`
+	Redact LoggerRedactConfig `mapstructure:"redact" yaml:"redact"`
+}
+
+// LoggerRedactConfig masks sensitive values before entries are encoded.
+// Redaction is on by default with the built-in key patterns and detectors.
+type LoggerRedactConfig struct {
+	Disable bool `mapstructure:"disable" yaml:"disable"`
+	// Keys are regular expressions matched case-insensitively against field
+	// keys, including keys nested in maps and structs; the whole value of a
+	// matching key is masked. Empty keeps the built-in patterns.
+	Keys []string `mapstructure:"keys" yaml:"keys"`
+	// Detectors mask matching parts of string values under any key: jwt,
+	// email and card. Empty enables all of them.
+	Detectors []string `mapstructure:"detectors" yaml:"detectors"`
+}
+
+// DefaultRedactKeys are used when logger.redact.keys is empty. A bare "code"
+// is left out because the request log uses it for the gRPC status code.
+var DefaultRedactKeys = []string{
+	`password|passwd|secret|token|authorization|cookie|dsn`,
+	`api_?key|access_?key|private_?key|credential`,
+	`(verify|verification|sms|captcha|otp)_?code`,
+}
+
+// KeyPattern compiles Keys, or DefaultRedactKeys when empty, into one
+// case-insensitive expression.
+func (c LoggerRedactConfig) KeyPattern() (*regexp.Regexp, error) {
+	keys := c.Keys
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+	patterns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, err := regexp.Compile(key); err != nil {
+			return nil, fmt.Errorf("logger redact key pattern %q: %w", key, err)
+		}
+		patterns = append(patterns, "(?:"+key+")")
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(patterns, "|")), nil
+}
+
+// LoggerSinkConfig configures one log output. Type selects a built-in sink
+// (console, file, syslog, journald, otlp) or one registered by the service;
+// the other fields apply to the types noted beside them.
+type LoggerSinkConfig struct {
+	Type string `mapstructure:"type" yaml:"type" validate:"required"`
+	// Level is the lowest level written by this sink, e.g. "warn"; entries
+	// must also pass logger.level. Empty writes every entry.
+	Level string `mapstructure:"level" yaml:"level" validate:"oneof=debug info warn error dpanic panic fatal"`
+	// Format is "json" (default) or "text" for console, file and syslog.
+	Format string `mapstructure:"format" yaml:"format" validate:"oneof=json text"`
+	// Color adds ANSI level colors to text output (console).
+	Color bool `mapstructure:"color" yaml:"color"`
+
+	// file
+	Path       string `mapstructure:"path" yaml:"path" validate:"required_if=type file"`
+	MaxSize    int    `mapstructure:"max_size" yaml:"max_size" validate:"min=0"`
+	MaxAge     int    `mapstructure:"max_age" yaml:"max_age" validate:"min=0"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups" validate:"min=0"`
+	Compress   bool   `mapstructure:"compress" yaml:"compress"`
+
+	// syslog and journald
+	Network  string `mapstructure:"network" yaml:"network" validate:"oneof=tcp udp unix unixgram"`
+	Address  string `mapstructure:"address" yaml:"address"`
+	Tag      string `mapstructure:"tag" yaml:"tag"`
+	Facility string `mapstructure:"facility" yaml:"facility"`
+
+	// otlp
+	Endpoint string            `mapstructure:"endpoint" yaml:"endpoint" validate:"required_if=type otlp"`
+	Insecure bool              `mapstructure:"insecure" yaml:"insecure"`
+	Headers  map[string]string `mapstructure:"headers" yaml:"headers" secret:"true"`
+}
+
+// LoggerSamplingConfig keeps the first Initial entries with the same level
+// and message per Tick, then every Thereafter-th one. Initial 0 disables
+// sampling; Tick defaults to 1s.
+type LoggerSamplingConfig struct {
+	Initial    int      `mapstructure:"initial" yaml:"initial" validate:"min=0"`
+	Thereafter int      `mapstructure:"thereafter" yaml:"thereafter" validate:"min=0"`
+	Tick       Duration `mapstructure:"tick" yaml:"tick" validate:"duration"`
+}
+
+// LoggerRateLimitConfig keeps at most Limit entries with the same level and
+// message per Window. Limit 0 disables it; Window defaults to 1m.
+type LoggerRateLimitConfig struct {
+	Limit  int      `mapstructure:"limit" yaml:"limit" validate:"min=0"`
+	Window Duration `mapstructure:"window" yaml:"window" validate:"duration"`
 }
 
 type MetricsConfig struct {
 	Enable bool   `mapstructure:"enable" yaml:"enable"`
-	Listen string `mapstructure:"listen" yaml:"listen"`
+	Listen string `mapstructure:"listen" yaml:"listen" validate:"required_if=enable true"`
 	Prefix string `mapstructure:"prefix" yaml:"prefix"`
+	// Callers lists the caller biz names kept in the caller label of the
+	// server metrics; other callers are labelled "other".
+	Callers []string `mapstructure:"callers" yaml:"callers"`
 }
 
 type MySQLConfig struct {
-	DSN  string          `mapstructure:"dsn" yaml:"dsn"`
+	DSN  string          `mapstructure:"dsn" yaml:"dsn" secret:"true"`
 	Pool MySQLPoolConfig `mapstructure:"pool" yaml:"pool"`
 }
 
 type MySQLPoolConfig struct {
-	MaxOpenConns int `mapstructure:"max_open_conns" yaml:"max_open_conns"`
-	MaxIdleConns int `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`
-	MaxLifetime  int `mapstructure:"max_lifetime" yaml:"max_lifetime"`
+	MaxOpenConns int      `mapstructure:"max_open_conns" yaml:"max_open_conns" validate:"min=0"`
+	MaxIdleConns int      `mapstructure:"max_idle_conns" yaml:"max_idle_conns" validate:"min=0"`
+	MaxLifetime  Duration `mapstructure:"max_lifetime" yaml:"max_lifetime" validate:"duration"`
 }
 
 type RedisConfig struct {
 	Addr             string   `mapstructure:"addr" yaml:"addr"`
 	Username         string   `mapstructure:"username" yaml:"username"`
-	Password         string   `mapstructure:"password" yaml:"password"`
-	DB               int      `mapstructure:"db" yaml:"db"`
+	Password         string   `mapstructure:"password" yaml:"password" secret:"true"`
+	DB               int      `mapstructure:"db" yaml:"db" validate:"min=0"`
 	MasterName       string   `mapstructure:"master_name" yaml:"master_name"`
 	SentinelAddrs    []string `mapstructure:"sentinel_addrs" yaml:"sentinel_addrs"`
 	SentinelUsername string   `mapstructure:"sentinel_username" yaml:"sentinel_username"`
-	SentinelPassword string   `mapstructure:"sentinel_password" yaml:"sentinel_password"`
+	SentinelPassword string   `mapstructure:"sentinel_password" yaml:"sentinel_password" secret:"true"`
 }
 
 type EtcdConfig struct {
@@ -101,47 +287,176 @@ type ConsulConfig struct {
 }
 
 // DiscoveryConfig selects how logical service names are resolved for gRPC
-// clients. Provider is either "dns" or "consul"; an empty provider disables
+// clients. Provider is "dns", "consul" or "etcd"; an empty provider disables
 // discovery until a caller requests a downstream connection.
 type DiscoveryConfig struct {
-	Provider        string            `mapstructure:"provider" yaml:"provider"`
-	RefreshInterval string            `mapstructure:"refresh_interval" yaml:"refresh_interval"`
-	Timeout         string            `mapstructure:"timeout" yaml:"timeout"`
+	Provider        string            `mapstructure:"provider" yaml:"provider" validate:"oneof=dns consul etcd none"`
+	RefreshInterval Duration          `mapstructure:"refresh_interval" yaml:"refresh_interval" validate:"duration"`
+	Timeout         Duration          `mapstructure:"timeout" yaml:"timeout" validate:"duration"`
 	Services        map[string]string `mapstructure:"services" yaml:"services"`
+	// DeadlineMargin is kept back from the remaining deadline of outgoing
+	// calls made through rpcclient.Pool; empty means 10ms.
+	DeadlineMargin Duration `mapstructure:"deadline_margin" yaml:"deadline_margin" validate:"duration"`
+	// Policies holds the per-service protection of outgoing calls, keyed by
+	// logical service name; the "*" entry applies to services without one.
+	Policies map[string]ServicePolicyConfig `mapstructure:"policies" yaml:"policies"`
+}
+
+type ServicePolicyConfig struct {
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker" yaml:"circuit_breaker"`
+	Bulkhead       BulkheadConfig       `mapstructure:"bulkhead" yaml:"bulkhead"`
+	Retry          RetryPolicyConfig    `mapstructure:"retry" yaml:"retry"`
+	Hedging        HedgingPolicyConfig  `mapstructure:"hedging" yaml:"hedging"`
+	RetryBudget    RetryBudgetConfig    `mapstructure:"retry_budget" yaml:"retry_budget"`
+}
+
+// RetryPolicyConfig retries failed calls up to MaxAttempts in total, waiting
+// a random backoff of up to InitialBackoff*BackoffMultiplier^(n-1), capped
+// at MaxBackoff, before retry n.
+type RetryPolicyConfig struct {
+	// MaxAttempts counts the first call; 0 or 1 turns retries off.
+	MaxAttempts int `mapstructure:"max_attempts" yaml:"max_attempts" validate:"min=0,max=5"`
+	// InitialBackoff is empty for 100ms.
+	InitialBackoff Duration `mapstructure:"initial_backoff" yaml:"initial_backoff" validate:"duration"`
+	// MaxBackoff is empty for 1s.
+	MaxBackoff Duration `mapstructure:"max_backoff" yaml:"max_backoff" validate:"duration"`
+	// BackoffMultiplier is 0 for 2.
+	BackoffMultiplier float64 `mapstructure:"backoff_multiplier" yaml:"backoff_multiplier" validate:"min=0"`
+	// Codes lists the retryable status codes by name, such as UNAVAILABLE;
+	// empty means UNAVAILABLE only.
+	Codes []string `mapstructure:"codes" yaml:"codes"`
+	// Methods are retried by gRPC itself through the service config of the
+	// connection. Entries are "pkg.Service", "pkg.Service/Method" or "*"
+	// for every method; list only methods that are safe to repeat.
+	Methods []string `mapstructure:"methods" yaml:"methods"`
+	// Idempotent also retries, in a client interceptor, unary methods whose
+	// proto definition sets idempotency_level to IDEMPOTENT or
+	// NO_SIDE_EFFECTS.
+	Idempotent bool `mapstructure:"idempotent" yaml:"idempotent"`
+}
+
+// HedgingPolicyConfig sends up to MaxAttempts copies of a call, one every
+// Delay until one succeeds, and keeps the first result that is not in
+// NonFatalCodes. It only applies to the listed methods.
+type HedgingPolicyConfig struct {
+	// Methods uses the format of RetryPolicyConfig.Methods; a method can
+	// have either a retry or a hedging policy.
+	Methods     []string `mapstructure:"methods" yaml:"methods"`
+	MaxAttempts int      `mapstructure:"max_attempts" yaml:"max_attempts" validate:"required_with=methods,min=2,max=5"`
+	// Delay is empty to send all copies at once.
+	Delay Duration `mapstructure:"delay" yaml:"delay" validate:"duration"`
+	// NonFatalCodes lists the status codes after which the other copies
+	// keep going, such as UNAVAILABLE.
+	NonFatalCodes []string `mapstructure:"non_fatal_codes" yaml:"non_fatal_codes"`
+}
+
+// RetryBudgetConfig keeps retries and hedged copies from amplifying an
+// outage: each failed call takes a token, each successful call returns
+// TokenRatio tokens, and no retry is sent while fewer than half of
+// MaxTokens are left.
+type RetryBudgetConfig struct {
+	// MaxTokens is 0 for 10.
+	MaxTokens int `mapstructure:"max_tokens" yaml:"max_tokens" validate:"min=0,max=1000"`
+	// TokenRatio is 0 for 0.1.
+	TokenRatio float64 `mapstructure:"token_ratio" yaml:"token_ratio" validate:"min=0"`
+}
+
+// CircuitBreakerConfig opens the breaker when, within Window and after at
+// least MinRequests calls, the failed or slow share of calls reaches its
+// ratio. Open breakers reject calls for OpenDuration, then let
+// HalfOpenRequests probes through: all succeeding closes the breaker, any
+// failing opens it again.
+type CircuitBreakerConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// FailureRatio is the share of calls failing with Unavailable,
+	// DeadlineExceeded, Internal, Unknown or DataLoss; 0 means 0.5.
+	FailureRatio float64 `mapstructure:"failure_ratio" yaml:"failure_ratio" validate:"min=0,max=1"`
+	// SlowCallDuration marks calls taking at least this long as slow; empty
+	// turns slow-call tracking off.
+	SlowCallDuration Duration `mapstructure:"slow_call_duration" yaml:"slow_call_duration" validate:"duration"`
+	// SlowCallRatio is the share of slow calls; 0 means 0.5.
+	SlowCallRatio float64 `mapstructure:"slow_call_ratio" yaml:"slow_call_ratio" validate:"min=0,max=1"`
+	// MinRequests is the number of calls in Window before the ratios apply;
+	// 0 means 20.
+	MinRequests int `mapstructure:"min_requests" yaml:"min_requests" validate:"min=0"`
+	// Window is the rolling window the ratios are measured over; empty means
+	// 10s.
+	Window Duration `mapstructure:"window" yaml:"window" validate:"duration"`
+	// OpenDuration is how long an open breaker rejects calls; empty means
+	// 30s.
+	OpenDuration Duration `mapstructure:"open_duration" yaml:"open_duration" validate:"duration"`
+	// HalfOpenRequests is the number of probe calls let through after
+	// OpenDuration; 0 means 3.
+	HalfOpenRequests int `mapstructure:"half_open_requests" yaml:"half_open_requests" validate:"min=0"`
+}
+
+// BulkheadConfig caps the concurrent unary calls to a service so a slow
+// dependency cannot hold every goroutine of the caller.
+type BulkheadConfig struct {
+	// MaxConcurrent is the number of calls in flight; 0 means unlimited.
+	MaxConcurrent int `mapstructure:"max_concurrent" yaml:"max_concurrent" validate:"min=0"`
+	// MaxWait is how long a call waits for a free slot before failing with
+	// ResourceExhausted; empty fails at once.
+	MaxWait Duration `mapstructure:"max_wait" yaml:"max_wait" validate:"duration"`
+}
+
+// Policy returns the policy of service, falling back to the "*" entry.
+func (c DiscoveryConfig) Policy(service string) ServicePolicyConfig {
+	if policy, ok := c.Policies[service]; ok {
+		return policy
+	}
+	return c.Policies["*"]
 }
 
 type RegistryConfig struct {
-	Provider    string                    `mapstructure:"provider" yaml:"provider"`
-	Name        string                    `mapstructure:"name" yaml:"name"`
-	Address     string                    `mapstructure:"address" yaml:"address"`
-	Port        int                       `mapstructure:"port" yaml:"port"`
+	Provider    string                    `mapstructure:"provider" yaml:"provider" validate:"oneof=consul etcd none"`
+	Name        string                    `mapstructure:"name" yaml:"name" validate:"required_if=provider consul|etcd"`
+	Address     string                    `mapstructure:"address" yaml:"address" validate:"required_if=provider consul|etcd"`
+	Port        int                       `mapstructure:"port" yaml:"port" validate:"required_if=provider consul|etcd,min=1,max=65535"`
 	HealthCheck RegistryHealthCheckConfig `mapstructure:"health_check" yaml:"health_check"`
+	// LeaseTTL is the etcd lease of the registration, in whole seconds;
+	// empty means 10s.
+	LeaseTTL Duration `mapstructure:"lease_ttl" yaml:"lease_ttl" validate:"duration"`
+	// Metadata is published with the instance: as service meta in Consul
+	// and in the endpoint record in etcd.
+	Metadata map[string]string `mapstructure:"metadata" yaml:"metadata"`
 }
 
 type RegistryHealthCheckConfig struct {
-	Interval string `mapstructure:"interval" yaml:"interval"`
-	Timeout  string `mapstructure:"timeout" yaml:"timeout"`
+	Interval Duration `mapstructure:"interval" yaml:"interval" validate:"duration"`
+	Timeout  Duration `mapstructure:"timeout" yaml:"timeout" validate:"duration"`
 }
 
 type SMTPConfig struct {
 	Host     string `mapstructure:"host" yaml:"host"`
-	Port     int    `mapstructure:"port" yaml:"port"`
+	Port     int    `mapstructure:"port" yaml:"port" validate:"min=1,max=65535"`
 	Username string `mapstructure:"username" yaml:"username"`
-	Password string `mapstructure:"password" yaml:"password"`
+	Password string `mapstructure:"password" yaml:"password" secret:"true"`
 }
 
 type JWTConfig struct {
-	AccessSecret  string `mapstructure:"access_secret" yaml:"access_secret"`
-	AccessExpire  int    `mapstructure:"access_expire" yaml:"access_expire"`
-	RefreshExpire int    `mapstructure:"refresh_expire" yaml:"refresh_expire"`
+	AccessSecret  string   `mapstructure:"access_secret" yaml:"access_secret" secret:"true"`
+	AccessExpire  Duration `mapstructure:"access_expire" yaml:"access_expire" validate:"duration"`
+	RefreshExpire Duration `mapstructure:"refresh_expire" yaml:"refresh_expire" validate:"duration"`
 }
 
 type OSSConfig struct {
-	Endpoint        string `mapstructure:"endpoint" yaml:"endpoint"`
-	AccessKeyID     string `mapstructure:"access_key_id" yaml:"access_key_id"`
-	AccessKeySecret string `mapstructure:"access_key_secret" yaml:"access_key_secret"`
-	BucketName      string `mapstructure:"bucket_name" yaml:"bucket_name"`
-	BaseURL         string `mapstructure:"base_url" yaml:"base_url"`
-	UseSSL          bool   `mapstructure:"use_ssl" yaml:"use_ssl"`
-	PresignExpire   int    `mapstructure:"presign_expire" yaml:"presign_expire"`
+	Endpoint        string   `mapstructure:"endpoint" yaml:"endpoint"`
+	AccessKeyID     string   `mapstructure:"access_key_id" yaml:"access_key_id"`
+	AccessKeySecret string   `mapstructure:"access_key_secret" yaml:"access_key_secret" secret:"true"`
+	BucketName      string   `mapstructure:"bucket_name" yaml:"bucket_name" validate:"required_with=endpoint"`
+	BaseURL         string   `mapstructure:"base_url" yaml:"base_url"`
+	UseSSL          bool     `mapstructure:"use_ssl" yaml:"use_ssl"`
+	PresignExpire   Duration `mapstructure:"presign_expire" yaml:"presign_expire" validate:"duration"`
+}
+
+// TracingConfig selects the OpenTelemetry span exporter. Exporter is "otlp",
+// "stdout" or "none"; an empty exporter disables export while still
+// propagating W3C trace context between services.
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter" yaml:"exporter" validate:"oneof=otlp stdout none"`
+	Endpoint    string  `mapstructure:"endpoint" yaml:"endpoint" validate:"required_if=exporter otlp"`
+	Insecure    bool    `mapstructure:"insecure" yaml:"insecure"`
+	ServiceName string  `mapstructure:"service_name" yaml:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio" yaml:"sample_ratio" validate:"min=0,max=1"`
 }
@@ -1,5 +1,8 @@
 package core
 
+// ILogger logs with fields given as in log/slog: alternating keys and
+// values, e.g. Info("login", "user_id", 7), or slog.Attr values. A bare
+// error is logged under the "error" key.
 type ILogger interface {
 	Log(...any) error
 	Debug(msg string, fields ...any)
@@ -8,7 +11,28 @@ type ILogger interface {
 	Error(msg string, fields ...any)
 	Fatal(msg string, fields ...any)
 	Panic(msg string, fields ...any)
+	// AddGlobalFields attaches fields to every later entry of this logger.
+	// It is meant for process-wide fields set once at startup; use With for
+	// fields of a request or an operation.
 	AddGlobalFields(fields ...any)
+	// With returns a child logger that adds fields to each of its entries,
+	// leaving the receiver unchanged.
+	With(fields ...any) ILogger
+}
+
+// IModuleLogger is implemented by loggers whose level can be tuned per
+// module through logger.modules.
+type IModuleLogger interface {
+	Module(name string) ILogger
+}
+
+// ModuleLogger returns the logger of a framework module such as "rpcclient",
+// or logger itself when it has no per-module levels.
+func ModuleLogger(logger ILogger, name string) ILogger {
+	if moduleLogger, ok := logger.(IModuleLogger); ok {
+		return moduleLogger.Module(name)
+	}
+	return logger
 }
 
 type LoggerOption func(l ILogger) error
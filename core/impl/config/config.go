@@ -3,9 +3,10 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"path"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
@@ -15,10 +16,30 @@ import (
 type Config struct {
 	core.Config `mapstructure:",squash"`
 
-	filepath string
-	viper    *viper.Viper
+	filepath  string
+	remotes   []remoteSource
+	envPrefix string
+	mode      string
+	profile   string
+	viper     *viper.Viper
+
+	secretResolvers map[string]SecretResolver
+
+	// mu guards the embedded core.Config and viper against concurrent
+	// reloads; reloadMu serializes reloads so subscribers observe changes
+	// in order.
+	mu          sync.RWMutex
+	reloadMu    sync.Mutex
+	subscribers []subscriber
+
+	watch         bool
+	onReloadError func(error)
+	onWarning     func(string)
+	watcher       *watcher
 }
 
+var _ core.IConfigWatcher = (*Config)(nil)
+
 type ConfigOption func(*Config) error
 
 func WithFilePath(filepath string) ConfigOption {
@@ -38,8 +59,15 @@ func NewConfig(opts ...ConfigOption) (*Config, error) {
 		}
 	}
 	if err := config.Reload(); err != nil {
+		_ = config.Close()
 		return nil, err
 	}
+	if config.watch && (config.filepath != "" || len(config.remotes) > 0) {
+		if err := config.startWatch(); err != nil {
+			_ = config.Close()
+			return nil, err
+		}
+	}
 	return config, nil
 }
 
@@ -48,125 +76,232 @@ func Load[T any](opts ...ConfigOption) (*T, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer config.Close()
 
 	var out T
 	if err := config.Unmarshal(&out); err != nil {
+		return nil, validateDecoded(err, &out)
+	}
+	if err := Validate(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
 
+// Unmarshal decodes the merged config into out. Environment overrides are
+// applied for every mapstructure path of out, so fields of business structs
+// loaded through Load[T] can be overridden like the core sections.
 func (ct *Config) Unmarshal(out any) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
 	if ct.viper == nil {
 		return cerrs.New("config viper not initialized")
 	}
-	if err := ct.viper.Unmarshal(out); err != nil {
+	applyEnvOverrides(ct.viper, reflect.TypeOf(out), ct.getEnvPrefix())
+	if err := ct.resolveSecrets(ct.viper); err != nil {
+		return err
+	}
+	if err := ct.viper.Unmarshal(out, decodeHook()); err != nil {
 		return cerrs.Wrap(err, fmt.Sprintf("unmarshal config file error,filepath:%s", ct.filepath))
 	}
 	return nil
 }
 
-func (ct *Config) GetMode() string { return ct.Mode }
-
-func (ct *Config) GetBizID() int { return ct.BizID }
+func (ct *Config) GetMode() string {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Mode
+}
 
-func (ct *Config) GetBizName() string { return ct.BizName }
+func (ct *Config) GetBizID() int {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.BizID
+}
 
-func (ct *Config) GetGRPC() core.GRPCConfig { return ct.GRPC }
+func (ct *Config) GetBizName() string {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.BizName
+}
 
-func (ct *Config) GetHTTP() core.HTTPConfig { return ct.HTTP }
+func (ct *Config) GetGRPC() core.GRPCConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.GRPC
+}
 
-func (ct *Config) GetLogger() core.LoggerConfig { return ct.Logger }
+func (ct *Config) GetHTTP() core.HTTPConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.HTTP
+}
 
-func (ct *Config) GetMetrics() core.MetricsConfig { return ct.Metrics }
+func (ct *Config) GetLogger() core.LoggerConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Logger
+}
 
-func (ct *Config) GetMySQL() core.MySQLConfig { return ct.MySQL }
+func (ct *Config) GetMetrics() core.MetricsConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Metrics
+}
 
-func (ct *Config) GetRedis() core.RedisConfig { return ct.Redis }
+func (ct *Config) GetMySQL() core.MySQLConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.MySQL
+}
 
-func (ct *Config) GetEtcd() core.EtcdConfig { return ct.Etcd }
+func (ct *Config) GetRedis() core.RedisConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Redis
+}
 
-func (ct *Config) GetConsul() core.ConsulConfig { return ct.Consul }
+func (ct *Config) GetEtcd() core.EtcdConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Etcd
+}
 
-func (ct *Config) GetDiscovery() core.DiscoveryConfig { return ct.Discovery }
+func (ct *Config) GetConsul() core.ConsulConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Consul
+}
 
-func (ct *Config) GetRegistry() core.RegistryConfig { return ct.Registry }
+func (ct *Config) GetDiscovery() core.DiscoveryConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Discovery
+}
 
-func (ct *Config) GetSMTP() core.SMTPConfig { return ct.SMTP }
+func (ct *Config) GetRegistry() core.RegistryConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Registry
+}
 
-func (ct *Config) GetJWT() core.JWTConfig { return ct.JWT }
+func (ct *Config) GetSMTP() core.SMTPConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.SMTP
+}
 
-func (ct *Config) GetOSS() core.OSSConfig { return ct.OSS }
+func (ct *Config) GetJWT() core.JWTConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.JWT
+}
 
-func (ct *Config) Reload() error {
-	if ct.filepath != "" {
-		return ct.loadFromFile()
-	}
-	return nil
+func (ct *Config) GetOSS() core.OSSConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.OSS
 }
 
-func (ct *Config) loadFromFile() error {
-	dir := path.Dir(ct.filepath)
-	fileNameWithoutExt := path.Base(ct.filepath)
-	ext := path.Ext(ct.filepath)
-	fileNameWithoutExt = fileNameWithoutExt[:len(fileNameWithoutExt)-len(ext)]
-	configType := strings.TrimPrefix(ext, ".")
-	if configType == "" {
-		configType = "yaml"
-	}
+func (ct *Config) GetTracing() core.TracingConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.Tracing
+}
 
-	ct.viper.SetConfigType(configType)
-	ct.viper.AddConfigPath(dir)
-	ct.viper.SetConfigName(fileNameWithoutExt)
+func (ct *Config) GetTLS() core.TLSConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.TLS
+}
 
-	if err := ct.viper.ReadInConfig(); err != nil {
-		return cerrs.Wrap(err, fmt.Sprintf("reading config file error,filepath:%s", ct.filepath))
+// Reload reads the config file and remote sources again, re-applies
+// environment overrides and swaps the result in atomically. Subscribers of
+// changed sections are notified after the swap. A failed reload keeps the
+// previous values.
+func (ct *Config) Reload() error {
+	if ct.filepath == "" && len(ct.remotes) == 0 {
+		return nil
 	}
-	if err := ct.Unmarshal(ct); err != nil {
+	ct.reloadMu.Lock()
+	defer ct.reloadMu.Unlock()
+
+	v, next, profile, err := ct.load()
+	if err != nil {
 		return err
 	}
-	ct.applyEnvOverrides()
-	return nil
-}
 
-func (ct *Config) applyEnvOverrides() {
-	setStringFromEnv(&ct.MySQL.DSN, "MYSITE_MYSQL_DSN")
-	setStringFromEnv(&ct.Redis.Addr, "MYSITE_REDIS_ADDR")
-	setStringFromEnv(&ct.Redis.Username, "MYSITE_REDIS_USERNAME")
-	setStringFromEnv(&ct.Redis.Password, "MYSITE_REDIS_PASSWORD")
-	setStringFromEnv(&ct.Redis.MasterName, "MYSITE_REDIS_MASTER_NAME")
-	setStringSliceFromEnv(&ct.Redis.SentinelAddrs, "MYSITE_REDIS_SENTINEL_ADDRS")
-	setStringFromEnv(&ct.Redis.SentinelUsername, "MYSITE_REDIS_SENTINEL_USERNAME")
-	setStringFromEnv(&ct.Redis.SentinelPassword, "MYSITE_REDIS_SENTINEL_PASSWORD")
-	setStringFromEnv(&ct.SMTP.Host, "MYSITE_SMTP_HOST")
-	setStringFromEnv(&ct.SMTP.Username, "MYSITE_SMTP_USERNAME")
-	setStringFromEnv(&ct.SMTP.Password, "MYSITE_SMTP_PASSWORD")
-	setStringFromEnv(&ct.JWT.AccessSecret, "MYSITE_JWT_ACCESS_SECRET")
-	setStringFromEnv(&ct.OSS.Endpoint, "MYSITE_OSS_ENDPOINT")
-	setStringFromEnv(&ct.OSS.AccessKeyID, "MYSITE_OSS_ACCESS_KEY_ID")
-	setStringFromEnv(&ct.OSS.AccessKeySecret, "MYSITE_OSS_ACCESS_KEY_SECRET")
-	setStringFromEnv(&ct.OSS.BucketName, "MYSITE_OSS_BUCKET")
-	setStringFromEnv(&ct.OSS.BaseURL, "MYSITE_OSS_BASE_URL")
+	ct.mu.Lock()
+	old := ct.Config
+	ct.Config = next
+	ct.viper = v
+	ct.profile = profile
+	subscribers := append([]subscriber(nil), ct.subscribers...)
+	ct.mu.Unlock()
+
+	notifySubscribers(subscribers, old, next)
+	return nil
 }
 
-func setStringFromEnv(target *string, key string) {
-	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
-		*target = value
+// load builds a new snapshot: the base file, the profile overlay of the
+// mode, remote sources, environment overrides and secret references, in
+// increasing precedence. It also returns the overlay path, empty when the
+// mode has none.
+func (ct *Config) load() (*viper.Viper, core.Config, string, error) {
+	var next core.Config
+	var mode, profile string
+	v := viper.New()
+	if ct.filepath != "" {
+		if err := ct.loadFromFile(v); err != nil {
+			return nil, next, "", err
+		}
+		var err error
+		if mode, err = ct.resolveMode(v); err != nil {
+			return nil, next, "", err
+		}
+		if mode != "" {
+			if profile, err = ct.mergeProfile(v, mode); err != nil {
+				return nil, next, "", err
+			}
+		}
+	}
+	if len(ct.remotes) > 0 {
+		applyConnectionEnv(v, ct.getEnvPrefix())
+	}
+	for _, remote := range ct.remotes {
+		if err := mergeRemote(v, remote); err != nil {
+			return nil, next, "", err
+		}
+	}
+	applyEnvOverrides(v, reflect.TypeOf(next), ct.getEnvPrefix())
+	if mode != "" {
+		// WithMode outranks PREFIX_MODE, which the overrides just applied.
+		v.Set("mode", mode)
+	}
+	if err := ct.resolveSecrets(v); err != nil {
+		return nil, next, "", err
 	}
+	ct.convertLegacyDurations(v)
+	if err := v.Unmarshal(&next, decodeHook()); err != nil {
+		err = cerrs.Wrap(err, fmt.Sprintf("unmarshal config file error,filepath:%s", ct.filepath))
+		return nil, next, "", validateDecoded(err, &next)
+	}
+	if err := Validate(&next); err != nil {
+		return nil, next, "", err
+	}
+	return v, next, profile, nil
 }
 
-func setStringSliceFromEnv(target *[]string, key string) {
-	value := strings.TrimSpace(os.Getenv(key))
-	if value == "" {
-		return
+func (ct *Config) loadFromFile(v *viper.Viper) error {
+	configType := strings.TrimPrefix(path.Ext(ct.filepath), ".")
+	if configType == "" {
+		configType = "yaml"
 	}
+	v.SetConfigType(configType)
+	v.SetConfigFile(ct.filepath)
 
-	values := strings.Split(value, ",")
-	result := make([]string, 0, len(values))
-	for _, item := range values {
-		if item = strings.TrimSpace(item); item != "" {
-			result = append(result, item)
-		}
+	if err := v.ReadInConfig(); err != nil {
+		return cerrs.Wrap(err, fmt.Sprintf("reading config file error,filepath:%s", ct.filepath))
 	}
-	*target = result
+	return nil
 }
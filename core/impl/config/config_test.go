@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
 )
 
 func TestNewConfigReturnsIndependentInstances(t *testing.T) {
@@ -125,10 +128,10 @@ oss:
 	if conf.Redis.Username != "app" || conf.Redis.SentinelUsername != "sentinel-app" {
 		t.Fatalf("redis usernames = data:%q sentinel:%q", conf.Redis.Username, conf.Redis.SentinelUsername)
 	}
-	if conf.Registry.HealthCheck.Interval != "3s" {
-		t.Fatalf("registry health check interval = %q, want 3s", conf.Registry.HealthCheck.Interval)
+	if conf.Registry.HealthCheck.Interval != core.Duration(3*time.Second) {
+		t.Fatalf("registry health check interval = %v, want 3s", conf.Registry.HealthCheck.Interval)
 	}
-	if conf.Discovery.Provider != "dns" || conf.Discovery.Timeout != "3s" || conf.Discovery.Services["account"] != "dns:///account:10000" {
+	if conf.Discovery.Provider != "dns" || conf.Discovery.Timeout != core.Duration(3*time.Second) || conf.Discovery.Services["account"] != "dns:///account:10000" {
 		t.Fatalf("discovery config = %+v", conf.Discovery)
 	}
 	if conf.OSS.BucketName != "mysite" {
@@ -231,3 +234,193 @@ oss:
 		t.Fatalf("redis sentinel credentials not overridden: %+v", conf.Redis)
 	}
 }
+
+func TestReloadNotifiesChangedSections(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 0\njwt:\n  access_secret: old\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+
+	var loggerCalls, jwtCalls, anyCalls int
+	conf.Subscribe("logger", func(old, new core.Config) { loggerCalls++ })
+	conf.Subscribe("jwt", func(old, new core.Config) {
+		jwtCalls++
+		if old.JWT.AccessSecret != "old" || new.JWT.AccessSecret != "new" {
+			t.Errorf("jwt change = %q -> %q, want old -> new", old.JWT.AccessSecret, new.JWT.AccessSecret)
+		}
+	})
+	conf.Subscribe("", func(old, new core.Config) { anyCalls++ })
+
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 0\njwt:\n  access_secret: new\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	if err := conf.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if loggerCalls != 0 || jwtCalls != 1 || anyCalls != 1 {
+		t.Fatalf("calls logger=%d jwt=%d any=%d, want 0 1 1", loggerCalls, jwtCalls, anyCalls)
+	}
+	if got := conf.GetJWT().AccessSecret; got != "new" {
+		t.Fatalf("jwt secret = %q, want new", got)
+	}
+
+	if err := os.WriteFile(configPath, []byte("jwt: ["), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	if err := conf.Reload(); err == nil {
+		t.Fatal("reload of invalid config succeeded")
+	}
+	if got := conf.GetJWT().AccessSecret; got != "new" {
+		t.Fatalf("jwt secret after failed reload = %q, want new", got)
+	}
+}
+
+func TestWatchReloadsFileAndReappliesEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 0\njwt:\n  access_secret: file\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("MYSITE_JWT_ACCESS_SECRET", "env-secret")
+
+	conf, err := NewConfig(WithFilePath(configPath), WithWatch(), WithReloadErrorHandler(func(err error) {
+		t.Errorf("reload error: %v", err)
+	}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	t.Cleanup(func() { _ = conf.Close() })
+
+	changes := make(chan core.Config, 1)
+	conf.Subscribe("logger", func(old, new core.Config) { changes <- new })
+
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 1\njwt:\n  access_secret: file\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	select {
+	case next := <-changes:
+		if next.Logger.Level != 1 {
+			t.Fatalf("logger level = %d, want 1", next.Logger.Level)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not observed")
+	}
+	if got := conf.GetLogger().Level; got != 1 {
+		t.Fatalf("logger level = %d, want 1", got)
+	}
+	if got := conf.GetJWT().AccessSecret; got != "env-secret" {
+		t.Fatalf("jwt secret = %q, want env override after reload", got)
+	}
+	if err := conf.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestEnvPrefixOverridesEveryMapstructurePath(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	content := []byte(`
+mode: debug
+metrics:
+  enable: false
+  listen: :10090
+mysql:
+  pool:
+    max_open_conns: 10
+discovery:
+  services:
+    account: dns:///account:10000
+`)
+	if err := os.WriteFile(configPath, content, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("ORDERSVC_MODE", "release")
+	t.Setenv("ORDERSVC_METRICS_ENABLE", "true")
+	t.Setenv("ORDERSVC_MYSQL_POOL_MAX_OPEN_CONNS", "42")
+	t.Setenv("ORDERSVC_ETCD_ENDPOINTS", "etcd-0:2379, etcd-1:2379")
+	t.Setenv("ORDERSVC_DISCOVERY_SERVICES_BLOG", "dns:///blog:10000")
+	t.Setenv("ORDERSVC_TRACING_SAMPLE_RATIO", "0.5")
+	t.Setenv("MYSITE_MODE", "ignored")
+
+	conf, err := NewConfig(WithFilePath(configPath), WithEnvPrefix("ordersvc"))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if conf.GetMode() != "release" || !conf.GetMetrics().Enable || conf.GetMySQL().Pool.MaxOpenConns != 42 {
+		t.Fatalf("scalar overrides not applied: mode=%q metrics=%v pool=%+v", conf.GetMode(), conf.GetMetrics().Enable, conf.GetMySQL().Pool)
+	}
+	if endpoints := conf.GetEtcd().Endpoints; len(endpoints) != 2 || endpoints[1] != "etcd-1:2379" {
+		t.Fatalf("etcd endpoints = %+v", endpoints)
+	}
+	if services := conf.GetDiscovery().Services; services["account"] != "dns:///account:10000" || services["blog"] != "dns:///blog:10000" {
+		t.Fatalf("discovery services = %+v", services)
+	}
+	if conf.GetTracing().SampleRatio != 0.5 {
+		t.Fatalf("sample ratio = %v, want 0.5", conf.GetTracing().SampleRatio)
+	}
+}
+
+func TestEnvOverridesSkipMapsOfSections(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	content := []byte(`
+discovery:
+  policies:
+    account:
+      retry:
+        max_attempts: 2
+`)
+	if err := os.WriteFile(configPath, content, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("MYSITE_DISCOVERY_POLICIES_ACCOUNT_RETRY_MAX_ATTEMPTS", "3")
+
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if got := conf.GetDiscovery().Policy("account").Retry.MaxAttempts; got != 2 {
+		t.Fatalf("retry max attempts = %d, want the file value 2", got)
+	}
+}
+
+func TestLoadAppliesEnvOverridesToBusinessFields(t *testing.T) {
+	type businessConfig struct {
+		Config `mapstructure:",squash"`
+
+		Moderation struct {
+			ReviewerIDs []int         `mapstructure:"reviewer_ids"`
+			Timeout     time.Duration `mapstructure:"timeout"`
+			Enabled     bool          `mapstructure:"enabled"`
+		} `mapstructure:"moderation"`
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("grpc:\n  listen: :10000\nmoderation:\n  reviewer_ids: [1]\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("ORDERSVC_GRPC_LISTEN", ":20000")
+	t.Setenv("ORDERSVC_MODERATION_REVIEWER_IDS", "3,4")
+	t.Setenv("ORDERSVC_MODERATION_TIMEOUT", "1m30s")
+	t.Setenv("ORDERSVC_MODERATION_ENABLED", "true")
+
+	conf, err := Load[businessConfig](WithFilePath(configPath), WithEnvPrefix("ORDERSVC"))
+	if err != nil {
+		t.Fatalf("load business config: %v", err)
+	}
+	if conf.GRPC.Listen != ":20000" {
+		t.Fatalf("grpc listen = %q, want env override", conf.GRPC.Listen)
+	}
+	if ids := conf.Moderation.ReviewerIDs; len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Fatalf("reviewer ids = %+v, want [3 4]", ids)
+	}
+	if conf.Moderation.Timeout != 90*time.Second || !conf.Moderation.Enabled {
+		t.Fatalf("moderation = %+v", conf.Moderation)
+	}
+}
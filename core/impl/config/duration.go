@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// legacyDurationUnits lists fields that used to be plain integers in a
+// field specific unit. Integers there keep their old meaning with a
+// deprecation warning; new files should use duration strings such as "2h".
+var legacyDurationUnits = map[string]time.Duration{
+	"mysql.pool.max_lifetime": time.Second,
+	"jwt.access_expire":       time.Hour,
+	"jwt.refresh_expire":      24 * time.Hour,
+	"oss.presign_expire":      time.Second,
+}
+
+var durationType = reflect.TypeOf(core.Duration(0))
+
+// WithWarningHandler receives deprecation warnings found while loading,
+// such as integer durations in their legacy unit. By default warnings are
+// written to stderr, since the logger is usually built from this config.
+func WithWarningHandler(fn func(msg string)) ConfigOption {
+	return func(c *Config) error {
+		if fn == nil {
+			return cerrs.New("warning handler is required")
+		}
+		c.onWarning = fn
+		return nil
+	}
+}
+
+func (ct *Config) warn(msg string) {
+	if ct.onWarning != nil {
+		ct.onWarning(msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "config %s: %s\n", ct.filepath, msg)
+}
+
+// convertLegacyDurations rewrites integers of the legacy fields into
+// duration strings before decoding, so the generic hook can read integers
+// as seconds everywhere else.
+func (ct *Config) convertLegacyDurations(v *viper.Viper) {
+	for path, unit := range legacyDurationUnits {
+		if !v.IsSet(path) {
+			continue
+		}
+		count, ok := integerValue(v.Get(path))
+		if !ok {
+			continue
+		}
+		converted := core.Duration(time.Duration(count) * unit)
+		v.Set(path, converted.String())
+		ct.warn(fmt.Sprintf("%s: integer %d is deprecated, use a duration string such as %q", path, count, converted.String()))
+	}
+}
+
+func integerValue(value any) (int64, bool) {
+	switch value := value.(type) {
+	case int:
+		return int64(value), true
+	case int64:
+		return value, true
+	case float64:
+		if value == float64(int64(value)) {
+			return int64(value), true
+		}
+	case string:
+		if count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			return count, true
+		}
+	}
+	return 0, false
+}
+
+// durationHook decodes core.Duration from "15m" style strings or integers
+// of seconds.
+func durationHook(_ reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != durationType {
+		return data, nil
+	}
+	value := reflect.ValueOf(data)
+	switch value.Kind() {
+	case reflect.String:
+		return core.ParseDuration(value.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return core.Duration(time.Duration(value.Int()) * time.Second), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return core.Duration(time.Duration(value.Uint()) * time.Second), nil
+	case reflect.Float32, reflect.Float64:
+		return core.Duration(value.Float() * float64(time.Second)), nil
+	}
+	return data, nil
+}
+
+func decodeHook() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		durationHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+)
+
+func TestDurationsAcceptStringsAndLegacyIntegers(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	content := []byte(`
+mysql:
+  pool:
+    max_lifetime: 300
+jwt:
+  access_expire: 2
+  refresh_expire: 7
+oss:
+  presign_expire: 15m
+discovery:
+  refresh_interval: 1m30s
+  timeout: 3
+`)
+	if err := os.WriteFile(configPath, content, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("MYSITE_JWT_REFRESH_EXPIRE", "14")
+
+	var warnings []string
+	conf, err := NewConfig(WithFilePath(configPath), WithWarningHandler(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  core.Duration
+		want time.Duration
+	}{
+		{name: "mysql.pool.max_lifetime", got: conf.GetMySQL().Pool.MaxLifetime, want: 5 * time.Minute},
+		{name: "jwt.access_expire", got: conf.GetJWT().AccessExpire, want: 2 * time.Hour},
+		{name: "jwt.refresh_expire", got: conf.GetJWT().RefreshExpire, want: 14 * 24 * time.Hour},
+		{name: "oss.presign_expire", got: conf.GetOSS().PresignExpire, want: 15 * time.Minute},
+		{name: "discovery.refresh_interval", got: conf.GetDiscovery().RefreshInterval, want: 90 * time.Second},
+		{name: "discovery.timeout", got: conf.GetDiscovery().Timeout, want: 3 * time.Second},
+	}
+	for _, check := range checks {
+		if check.got.Duration() != check.want {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+
+	joined := strings.Join(warnings, "\n")
+	for _, path := range []string{"mysql.pool.max_lifetime", "jwt.access_expire", "jwt.refresh_expire"} {
+		if !strings.Contains(joined, path) {
+			t.Errorf("missing deprecation warning for %s in %q", path, joined)
+		}
+	}
+	if strings.Contains(joined, "oss.presign_expire") || strings.Contains(joined, "discovery.timeout") {
+		t.Errorf("unexpected deprecation warning in %q", joined)
+	}
+	if !strings.Contains(joined, `"336h"`) {
+		t.Errorf("warning does not suggest the converted value: %q", joined)
+	}
+}
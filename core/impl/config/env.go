@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"github.com/spf13/viper"
+)
+
+// defaultEnvPrefix keeps the variable names existing deployments already
+// inject; new services should pick their own prefix with WithEnvPrefix.
+const defaultEnvPrefix = "MYSITE"
+
+// envAliases lists legacy variable names, without prefix, that predate the
+// generic path mapping. The generic name wins when both are set.
+var envAliases = map[string][]string{
+	"oss.bucket_name": {"OSS_BUCKET"},
+}
+
+// WithEnvPrefix sets the prefix of environment overrides. Every mapstructure
+// path maps to PREFIX_PATH with dots replaced by underscores, e.g.
+// ORDERSVC_MYSQL_POOL_MAX_OPEN_CONNS for mysql.pool.max_open_conns.
+func WithEnvPrefix(prefix string) ConfigOption {
+	return func(c *Config) error {
+		prefix = strings.Trim(strings.ToUpper(strings.TrimSpace(prefix)), "_")
+		if prefix == "" {
+			return cerrs.New("env prefix is required")
+		}
+		c.envPrefix = prefix
+		return nil
+	}
+}
+
+func (ct *Config) getEnvPrefix() string {
+	if ct.envPrefix == "" {
+		return defaultEnvPrefix
+	}
+	return ct.envPrefix
+}
+
+// applyEnvOverrides sets every mapstructure path of t that has a non-empty
+// environment variable on v. Values are set as strings and converted by the
+// decode hooks of Unmarshal, so ints, bools and durations work like in the
+// file; slices are comma separated. Map fields of scalars accept one
+// variable per key, e.g. PREFIX_DISCOVERY_SERVICES_ACCOUNT.
+func applyEnvOverrides(v *viper.Viper, t reflect.Type, prefix string) {
+	walkConfigPaths(t, "", func(path string, field reflect.Type) {
+		name := envName(prefix, path)
+		switch field.Kind() {
+		case reflect.Map:
+			if elem := field.Elem(); elem.Kind() == reflect.Struct || elem.Kind() == reflect.Pointer {
+				// Maps of sections, such as discovery.policies, have no
+				// flat variable form either.
+				return
+			}
+			applyMapEnv(v, path, name+"_")
+			return
+		case reflect.Slice, reflect.Array:
+			if elem := field.Elem(); elem.Kind() == reflect.Struct || elem.Kind() == reflect.Pointer {
+				// Lists of sections, such as logger.sinks, have no flat
+				// variable form; set them in the file or a remote source.
+				return
+			}
+			for _, alias := range envAliases[path] {
+				setSliceFromEnv(v, path, envName(prefix, alias))
+			}
+			setSliceFromEnv(v, path, name)
+			return
+		}
+		for _, alias := range envAliases[path] {
+			setStringFromEnv(v, path, envName(prefix, alias))
+		}
+		setStringFromEnv(v, path, name)
+	})
+}
+
+// applyConnectionEnv applies the overrides of the consul and etcd sections
+// before remote sources connect with them; applyEnvOverrides sets them again
+// over the remote layers.
+func applyConnectionEnv(v *viper.Viper, prefix string) {
+	applyEnvOverrides(v, reflect.TypeOf(struct {
+		Consul core.ConsulConfig `mapstructure:"consul"`
+		Etcd   core.EtcdConfig   `mapstructure:"etcd"`
+	}{}), prefix)
+}
+
+// walkConfigPaths visits the leaf paths of t the way mapstructure decodes
+// them: squashed embedded structs share the parent path and untagged fields
+// use their lower-cased name.
+func walkConfigPaths(t reflect.Type, parent string, visit func(path string, field reflect.Type)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if strings.Contains(options, "squash") {
+			walkConfigPaths(fieldType, parent, visit)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := name
+		if parent != "" {
+			path = parent + "." + name
+		}
+		if fieldType.Kind() == reflect.Struct && !isScalarStruct(fieldType) {
+			walkConfigPaths(fieldType, path, visit)
+			continue
+		}
+		visit(path, fieldType)
+	}
+}
+
+// isScalarStruct reports struct types decoded from a single value, such as
+// time.Time, which must not be walked into.
+func isScalarStruct(t reflect.Type) bool {
+	return t.PkgPath() == "time"
+}
+
+func envName(prefix, path string) string {
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+func setStringFromEnv(v *viper.Viper, path, key string) {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		v.Set(path, value)
+	}
+}
+
+func setSliceFromEnv(v *viper.Viper, path, key string) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return
+	}
+
+	values := strings.Split(value, ",")
+	result := make([]string, 0, len(values))
+	for _, item := range values {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	v.Set(path, result)
+}
+
+func applyMapEnv(v *viper.Viper, path, keyPrefix string) {
+	for _, entry := range os.Environ() {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, keyPrefix) || len(key) == len(keyPrefix) {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			v.Set(path+"."+strings.ToLower(strings.TrimPrefix(key, keyPrefix)), value)
+		}
+	}
+}
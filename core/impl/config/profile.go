@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeyPattern flags keys of business sections that look like
+// credentials, so Dump redacts them without a secret tag.
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|dsn|private_key|access_key|api_key|credential|authorization)`)
+
+var modePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// WithMode selects the profile overlay, typically from a command line flag.
+// It takes precedence over the PREFIX_MODE environment variable and the
+// mode key of the base file. An empty mode is ignored, so an unset flag
+// falls through to the other sources.
+func WithMode(mode string) ConfigOption {
+	return func(c *Config) error {
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if mode == "" {
+			return nil
+		}
+		if !modePattern.MatchString(mode) {
+			return cerrs.New(fmt.Sprintf("invalid config mode %q", mode))
+		}
+		c.mode = mode
+		return nil
+	}
+}
+
+// resolveMode picks the mode from WithMode, then PREFIX_MODE, then the mode
+// key of the base file.
+func (ct *Config) resolveMode(base *viper.Viper) (string, error) {
+	mode := ct.mode
+	if mode == "" {
+		mode = os.Getenv(envName(ct.getEnvPrefix(), "mode"))
+	}
+	if strings.TrimSpace(mode) == "" {
+		mode = base.GetString("mode")
+	}
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != "" && !modePattern.MatchString(mode) {
+		return "", cerrs.New(fmt.Sprintf("invalid config mode %q", mode))
+	}
+	return mode, nil
+}
+
+// profilePath returns the overlay next to the base file, e.g.
+// config/config.prod.yaml for config/config.yaml in mode prod.
+func profilePath(filepath, mode string) string {
+	ext := path.Ext(filepath)
+	return strings.TrimSuffix(filepath, ext) + "." + mode + ext
+}
+
+// mergeProfile deep-merges the overlay of mode over v. Maps merge key by
+// key; scalars and lists in the overlay replace the base value. A missing
+// overlay is not an error, so modes without overrides need no file.
+func (ct *Config) mergeProfile(v *viper.Viper, mode string) (string, error) {
+	profile := profilePath(ct.filepath, mode)
+	if _, err := os.Stat(profile); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	layer := viper.New()
+	configType := strings.TrimPrefix(path.Ext(profile), ".")
+	if configType == "" {
+		configType = "yaml"
+	}
+	layer.SetConfigType(configType)
+	layer.SetConfigFile(profile)
+	if err := layer.ReadInConfig(); err != nil {
+		return "", cerrs.Wrap(err, fmt.Sprintf("reading config profile error,filepath:%s", profile))
+	}
+	if err := v.MergeConfigMap(layer.AllSettings()); err != nil {
+		return "", cerrs.Wrap(err, fmt.Sprintf("merging config profile error,filepath:%s", profile))
+	}
+	return profile, nil
+}
+
+// Dump writes the merged config as YAML for debugging, with every layer,
+// environment override and resolved secret applied. Secret fields and keys
+// that look like credentials are replaced by [REDACTED].
+func (ct *Config) Dump(w io.Writer) error {
+	ct.mu.RLock()
+	settings := ct.viper.AllSettings()
+	profile := ct.profile
+	ct.mu.RUnlock()
+
+	settings = redactSettings(settings, "", secretPaths(reflect.TypeOf(core.Config{})))
+	sources := []string{ct.filepath}
+	if profile != "" {
+		sources = append(sources, profile)
+	}
+	for _, remote := range ct.remotes {
+		sources = append(sources, remote.String())
+	}
+	if _, err := fmt.Fprintf(w, "# sources: %s\n", strings.Join(sources, ", ")); err != nil {
+		return cerrs.Wrap(err)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(settings); err != nil {
+		return cerrs.Wrap(err, "encoding config dump error")
+	}
+	return encoder.Close()
+}
+
+// secretPaths lists the mapstructure paths of fields tagged secret:"true".
+func secretPaths(t reflect.Type) map[string]bool {
+	paths := make(map[string]bool)
+	var walk func(t reflect.Type, parent string)
+	walk = func(t reflect.Type, parent string) {
+		// Elements of lists share the path of the list, as in Dump.
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if strings.Contains(options, "squash") {
+				walk(field.Type, parent)
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fieldPath := joinPath(parent, name)
+			if field.Tag.Get("secret") == "true" {
+				paths[fieldPath] = true
+			}
+			walk(field.Type, fieldPath)
+		}
+	}
+	walk(t, "")
+	return paths
+}
+
+// redactSettings returns a copy of settings with secrets replaced. The maps
+// of viper.AllSettings share nested maps with the viper instance, so they
+// are never written to.
+func redactSettings(settings map[string]any, parent string, secrets map[string]bool) map[string]any {
+	out := make(map[string]any, len(settings))
+	for key, value := range settings {
+		keyPath := joinPath(parent, key)
+		switch value := value.(type) {
+		case map[string]any:
+			if secrets[keyPath] {
+				section := make(map[string]any, len(value))
+				for nested := range value {
+					section[nested] = redacted
+				}
+				out[key] = section
+				continue
+			}
+			out[key] = redactSettings(value, keyPath, secrets)
+		case []any:
+			items := make([]any, len(value))
+			for i, item := range value {
+				if section, ok := item.(map[string]any); ok {
+					item = redactSettings(section, keyPath, secrets)
+				}
+				items[i] = item
+			}
+			out[key] = items
+		default:
+			out[key] = value
+			if secrets[keyPath] || secretKeyPattern.MatchString(key) {
+				if value != nil && fmt.Sprint(value) != "" {
+					out[key] = redacted
+				}
+			}
+		}
+	}
+	return out
+}
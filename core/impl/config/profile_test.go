@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+)
+
+const profileBase = `mode: dev
+mysql:
+  dsn: root:secret@tcp(localhost:3306)/app
+  pool:
+    max_open_conns: 10
+    max_idle_conns: 5
+jwt:
+  access_secret: base-secret
+  access_expire: 1h
+`
+
+func writeProfileFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+func TestModeProfileDeepMergesOverBaseFile(t *testing.T) {
+	configPath := writeProfileFiles(t, map[string]string{
+		"config.yaml":      profileBase,
+		"config.dev.yaml":  "mysql:\n  pool:\n    max_open_conns: 2\n",
+		"config.prod.yaml": "mysql:\n  pool:\n    max_open_conns: 100\njwt:\n  access_expire: 2h\n",
+	})
+
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if pool := conf.GetMySQL().Pool; pool.MaxOpenConns != 2 || pool.MaxIdleConns != 5 {
+		t.Fatalf("dev pool = %+v, want max_open_conns 2 and base max_idle_conns 5", pool)
+	}
+
+	t.Setenv("MYSITE_MODE", "prod")
+	conf, err = NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if conf.GetMode() != "prod" || conf.GetMySQL().Pool.MaxOpenConns != 100 || conf.GetJWT().AccessExpire.Duration() != 2*time.Hour {
+		t.Fatalf("env mode: mode=%q pool=%+v access_expire=%s, want prod overlay", conf.GetMode(), conf.GetMySQL().Pool, conf.GetJWT().AccessExpire)
+	}
+	if conf.GetJWT().AccessSecret != "base-secret" {
+		t.Fatalf("jwt secret = %q, want base value kept", conf.GetJWT().AccessSecret)
+	}
+
+	conf, err = NewConfig(WithFilePath(configPath), WithMode("staging"))
+	if err != nil {
+		t.Fatalf("missing overlay: %v", err)
+	}
+	if conf.GetMode() != "staging" || conf.GetMySQL().Pool.MaxOpenConns != 10 {
+		t.Fatalf("staging: mode=%q pool=%+v, want base values", conf.GetMode(), conf.GetMySQL().Pool)
+	}
+
+	if _, err := NewConfig(WithFilePath(configPath), WithMode("../prod")); err == nil {
+		t.Fatal("mode with a path separator was accepted")
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	configPath := writeProfileFiles(t, map[string]string{
+		"config.yaml":     profileBase + "payment:\n  api_token: tok-123\n  merchant: acme\n",
+		"config.dev.yaml": "jwt:\n  access_expire: 30m\n",
+	})
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := conf.Dump(&out); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	dump := out.String()
+	for _, leaked := range []string{"base-secret", "root:secret", "tok-123"} {
+		if strings.Contains(dump, leaked) {
+			t.Fatalf("dump leaks %q:\n%s", leaked, dump)
+		}
+	}
+	for _, want := range []string{"config.dev.yaml", "access_expire: 30m", "merchant: acme", "access_secret: '[REDACTED]'"} {
+		if !strings.Contains(dump, want) {
+			t.Fatalf("dump misses %q:\n%s", want, dump)
+		}
+	}
+}
+
+func TestDumpLeavesConfigUnredacted(t *testing.T) {
+	configPath := writeProfileFiles(t, map[string]string{
+		"config.yaml": profileBase + "logger:\n  sinks:\n    - type: otlp\n      endpoint: collector:4317\n      headers:\n        authorization: Bearer abc\n",
+	})
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := conf.Dump(io.Discard); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+
+	var got core.Config
+	if err := conf.Unmarshal(&got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.JWT.AccessSecret != "base-secret" || got.MySQL.DSN != "root:secret@tcp(localhost:3306)/app" {
+		t.Fatalf("secrets after dump = %q, %q", got.JWT.AccessSecret, got.MySQL.DSN)
+	}
+	if sinks := got.Logger.Sinks; len(sinks) != 1 || sinks[0].Headers["authorization"] != "Bearer abc" {
+		t.Fatalf("sink headers after dump = %+v", sinks)
+	}
+}
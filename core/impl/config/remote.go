@@ -0,0 +1,323 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/client/kvstore"
+	"github.com/iconnor-code/cogo/core"
+	"github.com/spf13/viper"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	remoteFetchTimeout  = 5 * time.Second
+	remoteRetryInterval = time.Second
+	consulWaitTime      = 5 * time.Minute
+)
+
+// remoteSource is a config layer stored in a key/value backend. Clients are
+// built lazily from the layers below it, and environment overrides of the
+// consul and etcd sections, with package kvstore, so the local file or the
+// environment can carry the consul.address or etcd.endpoints the source
+// connects to.
+type remoteSource interface {
+	String() string
+	fetch(ctx context.Context, base *viper.Viper) ([]byte, error)
+	// watch blocks until ctx is done and calls changed after the stored
+	// value was modified. Transient errors are passed to failed.
+	watch(ctx context.Context, changed func(), failed func(error))
+	close() error
+}
+
+// WithConsulKV layers the YAML or JSON document stored under key in Consul
+// KV over the file. The Consul address is read from consul.address of the
+// file, falling back to the CONSUL_HTTP_ADDR default of the Consul SDK.
+func WithConsulKV(key string) ConfigOption {
+	return func(c *Config) error {
+		key = strings.Trim(strings.TrimSpace(key), "/")
+		if key == "" {
+			return cerrs.New("consul kv key is required")
+		}
+		c.remotes = append(c.remotes, &consulSource{key: key})
+		return nil
+	}
+}
+
+// WithEtcdKey layers the YAML or JSON document stored under key in etcd
+// over the file. The endpoints are read from etcd.endpoints of the file.
+func WithEtcdKey(key string) ConfigOption {
+	return func(c *Config) error {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return cerrs.New("etcd key is required")
+		}
+		c.remotes = append(c.remotes, &etcdSource{key: key})
+		return nil
+	}
+}
+
+// mergeRemote parses the remote document and merges it over v. Keys ending
+// in .json are parsed as JSON; anything else as YAML, which accepts JSON too.
+func mergeRemote(v *viper.Viper, remote remoteSource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+	data, err := remote.fetch(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	configType := "yaml"
+	if strings.EqualFold(path.Ext(remote.String()), ".json") {
+		configType = "json"
+	}
+	layer := viper.New()
+	layer.SetConfigType(configType)
+	if err := layer.ReadConfig(bytes.NewReader(data)); err != nil {
+		return cerrs.Wrap(err, fmt.Sprintf("parsing remote config error,source:%s", remote))
+	}
+	if err := v.MergeConfigMap(layer.AllSettings()); err != nil {
+		return cerrs.Wrap(err, fmt.Sprintf("merging remote config error,source:%s", remote))
+	}
+	return nil
+}
+
+type consulSource struct {
+	key string
+
+	mu    sync.Mutex
+	kv    *api.KV
+	index uint64
+}
+
+func (s *consulSource) String() string { return "consul-kv:" + s.key }
+
+func (s *consulSource) connect(base *viper.Viper) (*api.KV, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kv != nil {
+		return s.kv, nil
+	}
+	kv, err := newConsulKV(base)
+	if err != nil {
+		return nil, err
+	}
+	s.kv = kv
+	return s.kv, nil
+}
+
+// newConsulKV builds a KV client from the consul section of v with
+// kvstore.NewConsul, shared by remote sources and consul-kv secrets.
+func newConsulKV(v *viper.Viper) (*api.KV, error) {
+	var conf core.ConsulConfig
+	if err := v.UnmarshalKey("consul", &conf, decodeHook()); err != nil {
+		return nil, cerrs.Wrap(err, "decoding consul config error")
+	}
+	conf.Address = strings.TrimSpace(conf.Address)
+	consul, err := kvstore.NewConsul(conf)
+	if err != nil {
+		return nil, cerrs.Wrap(err, "creating consul client error")
+	}
+	return consul.KV(), nil
+}
+
+func (s *consulSource) fetch(ctx context.Context, base *viper.Viper) ([]byte, error) {
+	kv, err := s.connect(base)
+	if err != nil {
+		return nil, err
+	}
+	pair, meta, err := kv.Get(s.key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, cerrs.Wrap(err, fmt.Sprintf("reading consul kv error,key:%s", s.key))
+	}
+	s.mu.Lock()
+	s.index = meta.LastIndex
+	s.mu.Unlock()
+	if pair == nil {
+		return nil, cerrs.New(fmt.Sprintf("consul kv key not found,key:%s", s.key))
+	}
+	return pair.Value, nil
+}
+
+// watch uses Consul blocking queries: each request waits until the index
+// moves past the one seen by the last fetch, so a change between the initial
+// load and the first query is not lost.
+func (s *consulSource) watch(ctx context.Context, changed func(), failed func(error)) {
+	s.mu.Lock()
+	kv, index := s.kv, s.index
+	s.mu.Unlock()
+	if kv == nil {
+		return
+	}
+	for ctx.Err() == nil {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: consulWaitTime}).WithContext(ctx)
+		_, meta, err := kv.Get(s.key, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failed(cerrs.Wrap(err, fmt.Sprintf("watching consul kv error,key:%s", s.key)))
+			sleepContext(ctx, remoteRetryInterval)
+			continue
+		}
+		switch {
+		case index == 0:
+			// Without a fetched index the first query only records it.
+		case meta.LastIndex < index:
+			// The index went backwards, e.g. after a Consul snapshot
+			// restore; reload and start over.
+			changed()
+		case meta.LastIndex > index:
+			changed()
+		}
+		index = meta.LastIndex
+	}
+}
+
+func (s *consulSource) close() error { return nil }
+
+type etcdSource struct {
+	key string
+
+	mu       sync.Mutex
+	client   *clientv3.Client
+	kv       clientv3.KV
+	watcher  clientv3.Watcher
+	revision int64
+}
+
+func (s *etcdSource) String() string { return "etcd:" + s.key }
+
+func (s *etcdSource) connect(base *viper.Viper) (clientv3.KV, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kv != nil {
+		return s.kv, nil
+	}
+	endpoints := base.GetStringSlice("etcd.endpoints")
+	if len(endpoints) == 0 {
+		return nil, cerrs.New(fmt.Sprintf("etcd endpoints are required for remote config,key:%s", s.key))
+	}
+	etcd, err := kvstore.NewEtcd(core.EtcdConfig{Endpoints: endpoints})
+	if err != nil {
+		return nil, cerrs.Wrap(err, "creating etcd client error")
+	}
+	s.client = etcd
+	s.kv = etcd
+	s.watcher = etcd
+	return s.kv, nil
+}
+
+func (s *etcdSource) fetch(ctx context.Context, base *viper.Viper) ([]byte, error) {
+	kv, err := s.connect(base)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := kv.Get(ctx, s.key)
+	if err != nil {
+		return nil, cerrs.Wrap(err, fmt.Sprintf("reading etcd key error,key:%s", s.key))
+	}
+	if resp.Header != nil {
+		s.mu.Lock()
+		s.revision = resp.Header.Revision
+		s.mu.Unlock()
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, cerrs.New(fmt.Sprintf("etcd key not found,key:%s", s.key))
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// watch follows the key from the revision of the last fetch. When the etcd
+// watch ends, e.g. because that revision was compacted or the member lost
+// its leader, the current revision is read before a reload is scheduled,
+// and the next watch starts after it: the reload runs asynchronously, so
+// the revision it fetches may not be recorded yet.
+func (s *etcdSource) watch(ctx context.Context, changed func(), failed func(error)) {
+	s.mu.Lock()
+	kv, watcher, revision := s.kv, s.watcher, s.revision
+	s.mu.Unlock()
+	if watcher == nil {
+		return
+	}
+	for ctx.Err() == nil {
+		s.follow(ctx, watcher, revision, changed, failed)
+		sleepContext(ctx, remoteRetryInterval)
+		if ctx.Err() != nil {
+			return
+		}
+		current, err := s.currentRevision(ctx, kv)
+		if err != nil {
+			// Watch from the old revision again; if it was compacted the
+			// watch fails at once and the next round retries the read.
+			failed(err)
+			continue
+		}
+		revision = current
+		changed()
+	}
+}
+
+// follow calls changed for every modification after revision, or from now
+// when revision is 0, until the watch ends.
+func (s *etcdSource) follow(ctx context.Context, watcher clientv3.Watcher, revision int64, changed func(), failed func(error)) {
+	var opts []clientv3.OpOption
+	if revision > 0 {
+		opts = append(opts, clientv3.WithRev(revision+1))
+	}
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	for resp := range watcher.Watch(watchCtx, s.key, opts...) {
+		if err := resp.Err(); err != nil {
+			failed(cerrs.Wrap(err, fmt.Sprintf("watching etcd key error,key:%s", s.key)))
+			continue
+		}
+		if len(resp.Events) > 0 {
+			changed()
+		}
+	}
+}
+
+func (s *etcdSource) currentRevision(ctx context.Context, kv clientv3.KV) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteFetchTimeout)
+	defer cancel()
+	resp, err := kv.Get(ctx, s.key, clientv3.WithCountOnly())
+	if err != nil {
+		return 0, cerrs.Wrap(err, fmt.Sprintf("reading etcd revision error,key:%s", s.key))
+	}
+	if resp.Header == nil {
+		return 0, nil
+	}
+	return resp.Header.Revision, nil
+}
+
+func (s *etcdSource) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client, s.kv, s.watcher = nil, nil, nil
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
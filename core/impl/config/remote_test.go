@@ -0,0 +1,299 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/spf13/viper"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeConsulKV serves a single key with Consul blocking query semantics.
+type fakeConsulKV struct {
+	mu      sync.Mutex
+	value   string
+	index   uint64
+	updated chan struct{}
+}
+
+func newFakeConsulKV(value string) *fakeConsulKV {
+	return &fakeConsulKV{value: value, index: 10, updated: make(chan struct{})}
+}
+
+func (f *fakeConsulKV) set(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = value
+	f.index++
+	close(f.updated)
+	f.updated = make(chan struct{})
+}
+
+func (f *fakeConsulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	waitIndex, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	f.mu.Lock()
+	if waitIndex != 0 && waitIndex >= f.index {
+		updated := f.updated
+		f.mu.Unlock()
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+		f.mu.Lock()
+	}
+	value, index := f.value, f.index
+	f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode([]map[string]any{{
+		"Key":         key,
+		"Value":       base64.StdEncoding.EncodeToString([]byte(value)),
+		"ModifyIndex": index,
+	}})
+}
+
+func TestConsulKVLayersOverFileAndWatchesChanges(t *testing.T) {
+	kv := newFakeConsulKV("discovery:\n  provider: dns\n  services:\n    blog: dns:///blog:10000\n")
+	server := httptest.NewServer(kv)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	content := "mode: debug\nconsul:\n  address: " + strings.TrimPrefix(server.URL, "http://") + "\ndiscovery:\n  provider: consul\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	conf, err := NewConfig(WithFilePath(configPath), WithConsulKV("services/account/config.yaml"), WithWatch(),
+		WithReloadErrorHandler(func(err error) { t.Errorf("reload error: %v", err) }))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	t.Cleanup(func() { _ = conf.Close() })
+
+	if conf.GetMode() != "debug" {
+		t.Fatalf("mode = %q, want file value", conf.GetMode())
+	}
+	if got := conf.GetDiscovery(); got.Provider != "dns" || got.Services["blog"] != "dns:///blog:10000" {
+		t.Fatalf("discovery = %+v, want consul layer", got)
+	}
+
+	changes := make(chan core.DiscoveryConfig, 1)
+	conf.Subscribe("discovery", func(old, new core.Config) { changes <- new.Discovery })
+	kv.set(`{"discovery": {"provider": "dns", "services": {"blog": "dns:///blog-v2:10000"}}}`)
+
+	select {
+	case got := <-changes:
+		if got.Services["blog"] != "dns:///blog-v2:10000" {
+			t.Fatalf("discovery after change = %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("consul kv change was not observed")
+	}
+}
+
+func TestConsulKVRejectsMissingKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("consul:\n  address: "+strings.TrimPrefix(server.URL, "http://")+"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := NewConfig(WithFilePath(configPath), WithConsulKV("missing")); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
+
+func TestConsulKVConnectsWithEnvAddress(t *testing.T) {
+	kv := newFakeConsulKV("jwt:\n  access_secret: consul-secret\n")
+	server := httptest.NewServer(kv)
+	t.Cleanup(server.Close)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("jwt:\n  access_secret: file\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("MYSITE_CONSUL_ADDRESS", strings.TrimPrefix(server.URL, "http://"))
+
+	conf, err := NewConfig(WithFilePath(configPath), WithConsulKV("services/account/config.yaml"))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if got := conf.GetJWT().AccessSecret; got != "consul-secret" {
+		t.Fatalf("jwt secret = %q, want the consul layer read from MYSITE_CONSUL_ADDRESS", got)
+	}
+}
+
+func TestEtcdSourceConnectsWithEnvEndpoints(t *testing.T) {
+	t.Setenv("MYSITE_ETCD_ENDPOINTS", "etcd-0:2379,etcd-1:2379")
+	v := viper.New()
+	applyConnectionEnv(v, "MYSITE")
+
+	source := &etcdSource{key: "/config/account.yaml"}
+	if _, err := source.connect(v); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = source.close() })
+	if got := source.client.Endpoints(); len(got) != 2 || got[1] != "etcd-1:2379" {
+		t.Fatalf("etcd endpoints = %v, want MYSITE_ETCD_ENDPOINTS", got)
+	}
+}
+
+type fakeEtcdKV struct {
+	clientv3.KV
+
+	mu       sync.Mutex
+	value    string
+	revision int64
+}
+
+func (f *fakeEtcdKV) set(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = value
+	f.revision++
+}
+
+func (f *fakeEtcdKV) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &clientv3.GetResponse{
+		Header: &etcdserverpb.ResponseHeader{Revision: f.revision},
+		Kvs:    []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(f.value)}},
+	}, nil
+}
+
+// fakeEtcdWatcher reports the start revision of every watch on starts and
+// ends a watch after passing on a canceled response.
+type fakeEtcdWatcher struct {
+	clientv3.Watcher
+
+	events chan clientv3.WatchResponse
+	starts chan int64
+}
+
+func (f *fakeEtcdWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	if f.starts != nil {
+		f.starts <- clientv3.OpGet(key, opts...).Rev()
+	}
+	out := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp := <-f.events:
+				out <- resp
+				if resp.Canceled {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func TestEtcdKeyLayersOverFileAndWatchesChanges(t *testing.T) {
+	kv := &fakeEtcdKV{value: `{"jwt": {"access_secret": "etcd-secret"}}`}
+	watcher := &fakeEtcdWatcher{events: make(chan clientv3.WatchResponse)}
+	source := &etcdSource{key: "/config/account.json", kv: kv, watcher: watcher}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("jwt:\n  access_secret: file\n  access_expire: 2h\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	withSource := func(c *Config) error {
+		c.remotes = append(c.remotes, source)
+		return nil
+	}
+	conf, err := NewConfig(WithFilePath(configPath), withSource, WithWatch())
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	t.Cleanup(func() { _ = conf.Close() })
+
+	if got := conf.GetJWT(); got.AccessSecret != "etcd-secret" || got.AccessExpire != core.Duration(2*time.Hour) {
+		t.Fatalf("jwt = %+v, want etcd secret layered over file", got)
+	}
+
+	changes := make(chan string, 1)
+	conf.Subscribe("jwt", func(old, new core.Config) { changes <- new.JWT.AccessSecret })
+	kv.set(`{"jwt": {"access_secret": "rotated"}}`)
+	watcher.events <- clientv3.WatchResponse{Events: []*clientv3.Event{{Type: clientv3.EventTypePut}}}
+
+	select {
+	case got := <-changes:
+		if got != "rotated" {
+			t.Fatalf("jwt secret = %q, want rotated", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("etcd change was not observed")
+	}
+}
+
+func TestEtcdKeyWatchesFromCurrentRevisionAfterCompaction(t *testing.T) {
+	kv := &fakeEtcdKV{value: `{"jwt": {"access_secret": "etcd-secret"}}`, revision: 5}
+	watcher := &fakeEtcdWatcher{events: make(chan clientv3.WatchResponse), starts: make(chan int64, 4)}
+	source := &etcdSource{key: "/config/account.json", kv: kv, watcher: watcher}
+	withSource := func(c *Config) error {
+		c.remotes = append(c.remotes, source)
+		return nil
+	}
+	conf, err := NewConfig(withSource, WithWatch())
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	t.Cleanup(func() { _ = conf.Close() })
+
+	nextStart := func() int64 {
+		t.Helper()
+		select {
+		case revision := <-watcher.starts:
+			return revision
+		case <-time.After(5 * time.Second):
+			t.Fatal("etcd watch was not started")
+			return 0
+		}
+	}
+	if got := nextStart(); got != 6 {
+		t.Fatalf("first watch starts at %d, want 6 after the fetched revision", got)
+	}
+
+	changes := make(chan string, 1)
+	conf.Subscribe("jwt", func(old, new core.Config) { changes <- new.JWT.AccessSecret })
+	// The key changes at revisions 6 and 7, which are then compacted away.
+	kv.set(`{"jwt": {"access_secret": "skipped"}}`)
+	kv.set(`{"jwt": {"access_secret": "rotated"}}`)
+	watcher.events <- clientv3.WatchResponse{CompactRevision: 7, Canceled: true}
+
+	if got := nextStart(); got != 8 {
+		t.Fatalf("watch after compaction starts at %d, want 8", got)
+	}
+	select {
+	case got := <-changes:
+		if got != "rotated" {
+			t.Fatalf("jwt secret = %q, want rotated", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change missed during compaction was not reloaded")
+	}
+}
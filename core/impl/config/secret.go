@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/spf13/viper"
+)
+
+// SecretResolver returns the secret a reference points to. ref is the part
+// after "scheme://", e.g. "/run/secrets/jwt" for file:///run/secrets/jwt.
+// Errors must not include the secret value.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// WithSecretResolver resolves string values starting with scheme:// through
+// resolver, replacing the built-in resolver of the same scheme. Built-in
+// schemes are file, env and consul-kv.
+func WithSecretResolver(scheme string, resolver SecretResolver) ConfigOption {
+	return func(c *Config) error {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if scheme == "" {
+			return cerrs.New("secret scheme is required")
+		}
+		if resolver == nil {
+			return cerrs.New(fmt.Sprintf("secret resolver is required,scheme:%s", scheme))
+		}
+		if c.secretResolvers == nil {
+			c.secretResolvers = make(map[string]SecretResolver)
+		}
+		c.secretResolvers[scheme] = resolver
+		return nil
+	}
+}
+
+// resolveSecrets replaces every string value of v holding a secret
+// reference with the resolved secret. It runs after environment overrides,
+// so references can be injected through env vars as well.
+func (ct *Config) resolveSecrets(v *viper.Viper) error {
+	resolvers := ct.secretResolversFor(v)
+	for _, key := range v.AllKeys() {
+		value, ok := v.Get(key).(string)
+		if !ok {
+			continue
+		}
+		scheme, ref, ok := strings.Cut(strings.TrimSpace(value), "://")
+		if !ok {
+			continue
+		}
+		resolver := resolvers[strings.ToLower(scheme)]
+		if resolver == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+		secret, err := resolver.Resolve(ctx, ref)
+		cancel()
+		if err != nil {
+			return cerrs.Wrap(err, fmt.Sprintf("resolving secret error,key:%s,scheme:%s", key, scheme))
+		}
+		v.Set(key, secret)
+	}
+	return nil
+}
+
+func (ct *Config) secretResolversFor(v *viper.Viper) map[string]SecretResolver {
+	resolvers := map[string]SecretResolver{
+		"file":      SecretResolverFunc(resolveFileSecret),
+		"env":       SecretResolverFunc(resolveEnvSecret),
+		"consul-kv": &consulSecretResolver{conf: v},
+	}
+	for scheme, resolver := range ct.secretResolvers {
+		resolvers[scheme] = resolver
+	}
+	return resolvers
+}
+
+// resolveFileSecret reads mounted secrets such as Kubernetes or Docker
+// secret files; a trailing newline is dropped.
+func resolveFileSecret(_ context.Context, ref string) (string, error) {
+	if ref == "" {
+		return "", cerrs.New("secret file path is required")
+	}
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", cerrs.Wrap(err, fmt.Sprintf("reading secret file error,filepath:%s", ref))
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolveEnvSecret(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok || value == "" {
+		return "", cerrs.New(fmt.Sprintf("secret env is not set,name:%s", ref))
+	}
+	return value, nil
+}
+
+// consulSecretResolver reads a Consul KV key with the client built from
+// the consul section of conf, created on first use.
+type consulSecretResolver struct {
+	conf *viper.Viper
+
+	once sync.Once
+	kv   *api.KV
+	err  error
+}
+
+func (r *consulSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	key := strings.Trim(ref, "/")
+	if key == "" {
+		return "", cerrs.New("secret consul kv key is required")
+	}
+	r.once.Do(func() {
+		r.kv, r.err = newConsulKV(r.conf)
+	})
+	if r.err != nil {
+		return "", r.err
+	}
+	pair, _, err := r.kv.Get(key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return "", cerrs.Wrap(err, fmt.Sprintf("reading consul kv error,key:%s", key))
+	}
+	if pair == nil {
+		return "", cerrs.New(fmt.Sprintf("consul kv key not found,key:%s", key))
+	}
+	return string(pair.Value), nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretReferencesAreResolvedAtLoad(t *testing.T) {
+	kv := newFakeConsulKV("consul-smtp-password")
+	server := httptest.NewServer(kv)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "jwt")
+	if err := os.WriteFile(secretPath, []byte("file-jwt-secret\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	configPath := filepath.Join(dir, "app.yaml")
+	content := "consul:\n  address: " + strings.TrimPrefix(server.URL, "http://") + `
+jwt:
+  access_secret: file://` + secretPath + `
+redis:
+  password: env://REDIS_PASSWORD_SECRET
+smtp:
+  password: consul-kv://secrets/smtp
+oss:
+  access_key_secret: vault://oss/secret
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("REDIS_PASSWORD_SECRET", "env-redis-password")
+	t.Setenv("MYSITE_MYSQL_DSN", "env://MYSQL_DSN_SECRET")
+	t.Setenv("MYSQL_DSN_SECRET", "root:pass@tcp(db:3306)/app")
+
+	vault := SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "vault:" + ref, nil
+	})
+	conf, err := NewConfig(WithFilePath(configPath), WithSecretResolver("vault", vault))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if got := conf.GetJWT().AccessSecret; got != "file-jwt-secret" {
+		t.Fatalf("jwt secret = %q, want file secret without newline", got)
+	}
+	if got := conf.GetRedis().Password; got != "env-redis-password" {
+		t.Fatalf("redis password = %q", got)
+	}
+	if got := conf.GetSMTP().Password; got != "consul-smtp-password" {
+		t.Fatalf("smtp password = %q", got)
+	}
+	if got := conf.GetOSS().AccessKeySecret; got != "vault:oss/secret" {
+		t.Fatalf("oss secret = %q", got)
+	}
+	if got := conf.GetMySQL().DSN; got != "root:pass@tcp(db:3306)/app" {
+		t.Fatalf("mysql dsn = %q, want env override resolved", got)
+	}
+}
+
+func TestSecretErrorsDoNotContainSecretValues(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("jwt:\n  access_secret: env://MISSING_JWT_SECRET\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	_, err := NewConfig(WithFilePath(configPath))
+	if err == nil || !strings.Contains(err.Error(), "jwt.access_secret") || !strings.Contains(err.Error(), "MISSING_JWT_SECRET") {
+		t.Fatalf("expected error naming key and env, got %v", err)
+	}
+
+	type businessConfig struct {
+		Config `mapstructure:",squash"`
+
+		APIKey string `mapstructure:"api_key" secret:"true" validate:"max=8"`
+	}
+	if err := os.WriteFile(configPath, []byte("api_key: env://API_KEY_SECRET\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("API_KEY_SECRET", "super-long-api-key")
+	_, err = Load[businessConfig](WithFilePath(configPath))
+	if err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Fatalf("expected api_key validation error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "super-long-api-key") {
+		t.Fatalf("validation error leaked secret: %v", err)
+	}
+}
@@ -0,0 +1,467 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/mitchellh/mapstructure"
+	"google.golang.org/grpc/codes"
+)
+
+// Validator is implemented by config structs with rules that struct tags
+// cannot express. Validate runs after the tag rules of the same struct;
+// returned errors are reported under the struct's path, and *FieldError
+// values name a field relative to it.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError names one invalid config field by its mapstructure path.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError aggregates every invalid field found in one pass.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Fields)+1)
+	lines = append(lines, fmt.Sprintf("invalid config, %d field(s):", len(e.Fields)))
+	for _, field := range e.Fields {
+		lines = append(lines, "  "+field.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the validate tags of every field reachable from v and
+// calls Validate on each struct implementing Validator. v is typically a
+// *Config or a business struct loaded through Load[T]; NewConfig, reloads
+// and Load[T] run it, so an invalid change keeps the previous config active.
+// Supported rules, separated by commas:
+//
+//	required               the field must not be zero
+//	required_if=PATH V1|V2 required when PATH equals one of the values
+//	required_with=PATH     required when PATH is not zero
+//	oneof=A B C            a non-empty value must be one of the words
+//	min=N, max=N           numeric bounds, or length bounds for strings and slices
+//	duration               a non-empty string must be a positive duration
+//
+// PATH is a sibling mapstructure key, or a dotted path from the root.
+func Validate(v any) error {
+	if ct, ok := v.(*Config); ok {
+		ct.mu.RLock()
+		conf := ct.Config
+		ct.mu.RUnlock()
+		v = &conf
+	}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	w := &validationWalker{root: value}
+	w.walk(value, "")
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: w.errs}
+}
+
+// redacted replaces secret values in messages and dumps.
+const redacted = "[REDACTED]"
+
+// decodeErrorPattern matches one entry of a mapstructure decoding error.
+var decodeErrorPattern = regexp.MustCompile(`^(?:error decoding )?'([^']*)':? (.*)$`)
+
+// decodeMapKeyPattern matches the map keys mapstructure writes as
+// policies[order], reported as policies.order like validation errors.
+var decodeMapKeyPattern = regexp.MustCompile(`\[([^\]]*[^\]0-9][^\]]*)\]`)
+
+// validateDecoded reports per-field decoding failures, such as "soon" for a
+// duration, together with the validation errors of the fields that did
+// decode. Other errors are returned unchanged.
+func validateDecoded(decodeErr error, out any) error {
+	var mapErr *mapstructure.Error
+	if !errors.As(decodeErr, &mapErr) {
+		return decodeErr
+	}
+	fields := make([]*FieldError, 0, len(mapErr.Errors))
+	failed := make(map[string]bool, len(mapErr.Errors))
+	for _, message := range mapErr.Errors {
+		match := decodeErrorPattern.FindStringSubmatch(message)
+		if match == nil {
+			fields = append(fields, &FieldError{Message: message})
+			continue
+		}
+		field := decodeMapKeyPattern.ReplaceAllString(match[1], ".$1")
+		failed[field] = true
+		fields = append(fields, &FieldError{Field: field, Message: match[2]})
+	}
+	var validationErr *ValidationError
+	if errors.As(Validate(out), &validationErr) {
+		for _, field := range validationErr.Fields {
+			// A field that failed to decode is zero and may also fail a
+			// required rule; its decoding error is the useful one.
+			if !failed[field.Field] {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return &ValidationError{Fields: fields}
+}
+
+type validationWalker struct {
+	root reflect.Value
+	errs []*FieldError
+}
+
+func (w *validationWalker) add(field, format string, args ...any) {
+	w.errs = append(w.errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (w *validationWalker) walk(value reflect.Value, path string) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || isScalarStruct(value.Type()) {
+		return
+	}
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if strings.Contains(options, "squash") {
+			w.walk(fieldValue, path)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fieldPath := joinPath(path, name)
+		if rules := field.Tag.Get("validate"); rules != "" {
+			w.checkRules(value, fieldValue, fieldPath, rules, field.Tag.Get("secret") == "true")
+		}
+		if fieldValue.Kind() == reflect.Slice {
+			// Lists of sections, such as logger.sinks, are checked per
+			// element as logger.sinks[0].type.
+			for j := 0; j < fieldValue.Len(); j++ {
+				w.walk(fieldValue.Index(j), fmt.Sprintf("%s[%d]", fieldPath, j))
+			}
+			continue
+		}
+		if fieldValue.Kind() == reflect.Map && fieldValue.Type().Key().Kind() == reflect.String {
+			// Sections keyed by name, such as discovery.policies, are checked
+			// per entry as discovery.policies.order.bulkhead.max_wait.
+			keys := fieldValue.MapKeys()
+			slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+			for _, key := range keys {
+				w.walk(fieldValue.MapIndex(key), joinPath(fieldPath, key.String()))
+			}
+			continue
+		}
+		w.walk(fieldValue, fieldPath)
+	}
+
+	if conf, ok := value.Interface().(core.Config); ok {
+		w.checkCoreConfig(conf, path)
+	}
+	if validator, ok := asValidator(value); ok {
+		w.collect(path, validator.Validate())
+	}
+}
+
+func asValidator(value reflect.Value) (Validator, bool) {
+	if validator, ok := value.Interface().(Validator); ok {
+		return validator, true
+	}
+	if value.CanAddr() {
+		validator, ok := value.Addr().Interface().(Validator)
+		return validator, ok
+	}
+	return nil, false
+}
+
+// collect flattens joined errors and prefixes them with the struct path.
+func (w *validationWalker) collect(path string, err error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			w.collect(path, e)
+		}
+		return
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		w.errs = append(w.errs, &FieldError{Field: joinPath(path, fieldErr.Field), Message: fieldErr.Message})
+		return
+	}
+	w.errs = append(w.errs, &FieldError{Field: path, Message: err.Error()})
+}
+
+// checkRules applies the tag rules of one field. Values of secret fields are
+// left out of the messages.
+func (w *validationWalker) checkRules(parent, value reflect.Value, path, rules string, secret bool) {
+	shown := fmt.Sprint(value.Interface())
+	if secret {
+		shown = redacted
+	}
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "required_if":
+			target, values, _ := strings.Cut(arg, " ")
+			if slices.Contains(strings.Split(values, "|"), normalize(w.lookup(parent, target))) {
+				required = true
+			}
+		case "required_with":
+			if target := w.lookup(parent, arg); target.IsValid() && !target.IsZero() {
+				required = true
+			}
+		}
+	}
+	if required && value.IsZero() {
+		w.add(path, "is required")
+		return
+	}
+	if value.IsZero() {
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "oneof":
+			if allowed := strings.Fields(arg); !slices.Contains(allowed, normalize(value)) {
+				w.add(path, "must be one of %s, got %q", strings.Join(allowed, ", "), shown)
+			}
+		case "min":
+			if limit, err := strconv.ParseFloat(arg, 64); err == nil && measure(value) < limit {
+				w.add(path, "must be at least %s, got %s", arg, shown)
+			}
+		case "max":
+			if limit, err := strconv.ParseFloat(arg, 64); err == nil && measure(value) > limit {
+				w.add(path, "must be at most %s, got %s", arg, shown)
+			}
+		case "duration":
+			if value.Kind() != reflect.String {
+				if value.Kind() == reflect.Int64 && value.Int() <= 0 {
+					w.add(path, "must be a positive duration, got %s", shown)
+				}
+				continue
+			}
+			if duration, err := time.ParseDuration(strings.TrimSpace(value.String())); err != nil || duration <= 0 {
+				w.add(path, "must be a positive duration such as 3s, got %q", shown)
+			}
+		}
+	}
+}
+
+// lookup resolves a sibling key, or a dotted path from the root.
+func (w *validationWalker) lookup(parent reflect.Value, path string) reflect.Value {
+	current := parent
+	if strings.Contains(path, ".") {
+		current = w.root
+	}
+	for _, key := range strings.Split(path, ".") {
+		current = fieldByKey(current, key)
+		if !current.IsValid() {
+			return current
+		}
+	}
+	return current
+}
+
+func fieldByKey(value reflect.Value, key string) reflect.Value {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if strings.Contains(options, "squash") {
+			if found := fieldByKey(value.Field(i), key); found.IsValid() {
+				return found
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return value.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func normalize(value reflect.Value) string {
+	if !value.IsValid() {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(fmt.Sprint(value.Interface())))
+}
+
+func measure(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len())
+	}
+	return 0
+}
+
+func joinPath(parent, name string) string {
+	switch {
+	case parent == "":
+		return name
+	case name == "":
+		return parent
+	}
+	return parent + "." + name
+}
+
+// checkCoreConfig holds the cross-section rules of the framework sections:
+// a section is only checked when a provider or feature that uses it is on.
+func (w *validationWalker) checkCoreConfig(conf core.Config, path string) {
+	for module, level := range conf.Logger.Modules {
+		if level < -1 || level > 5 {
+			w.add(joinPath(path, "logger.modules."+module), "must be between -1 and 5, got %d", level)
+		}
+	}
+	for i, pattern := range conf.Logger.Redact.Keys {
+		if _, err := regexp.Compile(pattern); err != nil {
+			w.add(joinPath(path, fmt.Sprintf("logger.redact.keys[%d]", i)), "must be a regular expression: %v", err)
+		}
+	}
+	for i, detector := range conf.Logger.Redact.Detectors {
+		switch strings.ToLower(strings.TrimSpace(detector)) {
+		case "jwt", "email", "card":
+		default:
+			w.add(joinPath(path, fmt.Sprintf("logger.redact.detectors[%d]", i)), "must be one of [jwt email card], got %q", detector)
+		}
+	}
+	services := make([]string, 0, len(conf.Discovery.Policies))
+	for service := range conf.Discovery.Policies {
+		services = append(services, service)
+	}
+	slices.Sort(services)
+	for _, service := range services {
+		w.checkServicePolicy(conf.Discovery.Policies[service], joinPath(path, "discovery.policies."+service))
+	}
+	if conf.TLS.Enable && strings.TrimSpace(conf.TLS.CAFile) == "" {
+		switch strings.ToLower(strings.TrimSpace(conf.TLS.ClientAuth)) {
+		case "verify_if_given", "require_and_verify":
+			w.add(joinPath(path, "tls.ca_file"), "is required when tls.client_auth verifies client certificates")
+		}
+	}
+	registryProvider := strings.ToLower(strings.TrimSpace(conf.Registry.Provider))
+	discoveryProvider := strings.ToLower(strings.TrimSpace(conf.Discovery.Provider))
+	if (registryProvider == "consul" || discoveryProvider == "consul") && strings.TrimSpace(conf.Consul.Address) == "" {
+		w.add(joinPath(path, "consul.address"), "is required when registry or discovery provider is consul")
+	}
+	if (registryProvider == "etcd" || discoveryProvider == "etcd") && len(conf.Etcd.Endpoints) == 0 {
+		w.add(joinPath(path, "etcd.endpoints"), "is required when registry or discovery provider is etcd")
+	}
+	if registryProvider == "etcd" && conf.Registry.LeaseTTL > 0 && conf.Registry.LeaseTTL < core.Duration(time.Second) {
+		w.add(joinPath(path, "registry.lease_ttl"), "must be at least 1s, got %s", conf.Registry.LeaseTTL)
+	}
+	if registryProvider == "consul" {
+		if conf.Registry.HealthCheck.Interval == 0 {
+			w.add(joinPath(path, "registry.health_check.interval"), "is required when registry provider is consul")
+		}
+		if conf.Registry.HealthCheck.Timeout == 0 {
+			w.add(joinPath(path, "registry.health_check.timeout"), "is required when registry provider is consul")
+		}
+	}
+}
+
+// checkServicePolicy checks the status code names and method names that end
+// up in the gRPC service config of a downstream connection.
+func (w *validationWalker) checkServicePolicy(policy core.ServicePolicyConfig, path string) {
+	checkCodes := func(field string, names []string) {
+		for i, name := range names {
+			var code codes.Code
+			if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(name))))); err != nil {
+				w.add(joinPath(path, fmt.Sprintf("%s[%d]", field, i)), "must be a gRPC status code name such as UNAVAILABLE, got %q", name)
+			}
+		}
+	}
+	checkCodes("retry.codes", policy.Retry.Codes)
+	checkCodes("hedging.non_fatal_codes", policy.Hedging.NonFatalCodes)
+
+	retried := make(map[string]bool, len(policy.Retry.Methods))
+	for i, method := range policy.Retry.Methods {
+		if !validPolicyMethod(method) {
+			w.add(joinPath(path, fmt.Sprintf("retry.methods[%d]", i)), `must be "*", "pkg.Service" or "pkg.Service/Method", got %q`, method)
+		}
+		retried[strings.TrimSpace(method)] = true
+	}
+	for i, method := range policy.Hedging.Methods {
+		field := joinPath(path, fmt.Sprintf("hedging.methods[%d]", i))
+		switch {
+		case !validPolicyMethod(method):
+			w.add(field, `must be "*", "pkg.Service" or "pkg.Service/Method", got %q`, method)
+		case retried[strings.TrimSpace(method)]:
+			w.add(field, "already has a retry policy, got %q", method)
+		}
+	}
+}
+
+func validPolicyMethod(method string) bool {
+	method = strings.TrimSpace(method)
+	if method == "*" {
+		return true
+	}
+	service, name, hasMethod := strings.Cut(method, "/")
+	return service != "" && !strings.ContainsAny(service, "/ ") && (!hasMethod || name != "" && !strings.ContainsAny(name, "/ "))
+}
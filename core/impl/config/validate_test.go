@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewConfigReportsEveryInvalidField(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	content := []byte(`
+logger:
+  level: 9
+  redact:
+    keys: ["pass(word"]
+    detectors: [jwt, phone]
+registry:
+  provider: consul
+  port: 70000
+  health_check:
+    interval: soon
+discovery:
+  provider: zookeeper
+  policies:
+    order:
+      circuit_breaker:
+        failure_ratio: 2
+    user:
+      bulkhead:
+        max_wait: soon
+    blog:
+      retry:
+        codes: [UNAVAILABLE, SOMETIMES]
+        methods: [user.UserService/Get]
+      hedging:
+        methods: [user.UserService/Get]
+tracing:
+  exporter: otlp
+  sample_ratio: 2
+tls:
+  enable: true
+  client_auth: require_and_verify
+`)
+	if err := os.WriteFile(configPath, content, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	_, err := NewConfig(WithFilePath(configPath))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	want := []string{
+		"logger.level",
+		"logger.redact.keys[0]",
+		"logger.redact.detectors[1]",
+		"registry.name",
+		"registry.address",
+		"registry.port",
+		"registry.health_check.interval",
+		"registry.health_check.timeout",
+		"discovery.provider",
+		"discovery.policies.order.circuit_breaker.failure_ratio",
+		"discovery.policies.user.bulkhead.max_wait",
+		"discovery.policies.blog.retry.codes[1]",
+		"discovery.policies.blog.hedging.max_attempts",
+		"discovery.policies.blog.hedging.methods[0]",
+		"consul.address",
+		"tracing.endpoint",
+		"tracing.sample_ratio",
+		"tls.cert_file",
+		"tls.key_file",
+		"tls.ca_file",
+	}
+	fields := make(map[string]bool)
+	for _, field := range validationErr.Fields {
+		fields[field.Field] = true
+	}
+	for _, field := range want {
+		if !fields[field] {
+			t.Errorf("missing error for %s in:\n%v", field, err)
+		}
+	}
+	if len(validationErr.Fields) != len(want) {
+		t.Errorf("got %d field errors, want %d:\n%v", len(validationErr.Fields), len(want), err)
+	}
+}
+
+type moderationConfig struct {
+	ReviewerIDs []int  `mapstructure:"reviewer_ids" validate:"min=1"`
+	Queue       string `mapstructure:"queue"`
+	Workers     int    `mapstructure:"workers"`
+}
+
+func (c moderationConfig) Validate() error {
+	var errs error
+	if c.Queue != "" && c.Workers == 0 {
+		errs = errors.Join(errs, &FieldError{Field: "workers", Message: "is required with queue"})
+	}
+	if strings.HasPrefix(c.Queue, "tmp-") {
+		errs = errors.Join(errs, errors.New("temporary queues are not allowed"))
+	}
+	return errs
+}
+
+func TestLoadRunsValidatorOfBusinessStructs(t *testing.T) {
+	type businessConfig struct {
+		Config `mapstructure:",squash"`
+
+		Moderation moderationConfig `mapstructure:"moderation"`
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("moderation:\n  reviewer_ids: []\n  queue: tmp-review\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	_, err := Load[businessConfig](WithFilePath(configPath))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"moderation.workers: is required with queue", "moderation: temporary queues are not allowed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestInvalidReloadKeepsPreviousConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("discovery:\n  provider: dns\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	conf, err := NewConfig(WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte("discovery:\n  provider: dns\n  timeout: -1s\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	if err := conf.Reload(); err == nil || !strings.Contains(err.Error(), "discovery.timeout") {
+		t.Fatalf("expected discovery.timeout error, got %v", err)
+	}
+	if got := conf.GetDiscovery().Timeout; got != 0 {
+		t.Fatalf("timeout = %v after rejected reload, want previous value", got)
+	}
+}
+
+func TestEtcdProvidersRequireEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("discovery:\n  provider: etcd\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := NewConfig(WithFilePath(configPath)); err == nil || !strings.Contains(err.Error(), "etcd.endpoints: is required when registry or discovery provider is etcd") {
+		t.Fatalf("expected etcd.endpoints error, got %v", err)
+	}
+
+	content := "registry:\n  provider: etcd\n  lease_ttl: 500ms\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	_, err := NewConfig(WithFilePath(configPath))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, field := range validationErr.Fields {
+		fields[field.Field] = true
+	}
+	for _, field := range []string{"registry.name", "registry.address", "registry.port", "registry.lease_ttl", "etcd.endpoints"} {
+		if !fields[field] {
+			t.Errorf("missing error for %s in:\n%v", field, err)
+		}
+	}
+
+	content = "discovery:\n  provider: etcd\nregistry:\n  provider: etcd\n  name: account\n  address: 10.0.0.1\n  port: 9000\netcd:\n  endpoints: [127.0.0.1:2379]\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	if _, err := NewConfig(WithFilePath(configPath)); err != nil {
+		t.Fatalf("new config with etcd endpoints: %v", err)
+	}
+}
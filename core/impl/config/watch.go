@@ -0,0 +1,247 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+)
+
+// reloadDebounce coalesces the burst of events editors and ConfigMap
+// updates produce for a single change.
+const reloadDebounce = 100 * time.Millisecond
+
+type subscriber struct {
+	section string
+	fn      func(old, new core.Config)
+}
+
+type watcher struct {
+	fsWatcher *fsnotify.Watcher
+	changes   chan struct{}
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// WithWatch reloads the config whenever its file or a remote source
+// changes. Kubernetes ConfigMap volumes are supported because the directory
+// is watched and symlink swaps are detected. Call Close to stop watching.
+func WithWatch() ConfigOption {
+	return func(c *Config) error {
+		c.watch = true
+		return nil
+	}
+}
+
+// WithReloadErrorHandler receives errors from watched reloads. The previous
+// config stays active after a failed reload. By default errors are written
+// to stderr.
+func WithReloadErrorHandler(fn func(error)) ConfigOption {
+	return func(c *Config) error {
+		if fn == nil {
+			return cerrs.New("reload error handler is required")
+		}
+		c.onReloadError = fn
+		return nil
+	}
+}
+
+// Subscribe registers fn for changes of a top-level section, named by its
+// mapstructure key ("logger", "discovery", "jwt", ...). An empty section
+// matches any change. fn runs on the reloading goroutine after the new
+// values are visible through the getters.
+func (ct *Config) Subscribe(section string, fn func(old, new core.Config)) {
+	if fn == nil {
+		return
+	}
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.subscribers = append(ct.subscribers, subscriber{
+		section: strings.ToLower(strings.TrimSpace(section)),
+		fn:      fn,
+	})
+}
+
+// Close stops watching and releases remote source clients. It is safe to
+// call more than once.
+func (ct *Config) Close() error {
+	ct.mu.Lock()
+	w := ct.watcher
+	ct.watcher = nil
+	ct.mu.Unlock()
+
+	var errs error
+	if w != nil {
+		w.closeOnce.Do(func() {
+			w.cancel()
+			if w.fsWatcher != nil {
+				errs = w.fsWatcher.Close()
+			}
+			w.wg.Wait()
+		})
+	}
+	for _, remote := range ct.remotes {
+		if err := remote.close(); err != nil {
+			errs = errors.Join(errs, cerrs.Wrap(err, fmt.Sprintf("closing remote config error,source:%s", remote)))
+		}
+	}
+	return errs
+}
+
+func (ct *Config) startWatch() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watcher{changes: make(chan struct{}, 1), cancel: cancel}
+	if ct.filepath != "" {
+		fsWatcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			cancel()
+			return cerrs.Wrap(err, "creating config watcher error")
+		}
+		if err := fsWatcher.Add(filepath.Dir(filepath.Clean(ct.filepath))); err != nil {
+			cancel()
+			_ = fsWatcher.Close()
+			return cerrs.Wrap(err, fmt.Sprintf("watching config file error,filepath:%s", ct.filepath))
+		}
+		w.fsWatcher = fsWatcher
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			ct.watchFile(w)
+		}()
+	}
+	for _, remote := range ct.remotes {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			remote.watch(ctx, w.changed, ct.reloadFailed)
+		}()
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ct.reloadLoop(ctx, w)
+	}()
+
+	ct.mu.Lock()
+	ct.watcher = w
+	ct.mu.Unlock()
+	return nil
+}
+
+// changed schedules a reload without blocking the caller; bursts collapse
+// into the single pending signal.
+func (w *watcher) changed() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (ct *Config) reloadLoop(ctx context.Context, w *watcher) {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.changes:
+			debounce = time.After(reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			if err := ct.Reload(); err != nil {
+				ct.reloadFailed(err)
+			}
+		}
+	}
+}
+
+func (ct *Config) watchFile(w *watcher) {
+	file := filepath.Clean(ct.filepath)
+	realFile, _ := filepath.EvalSymlinks(file)
+	for {
+		select {
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			currentFile, _ := filepath.EvalSymlinks(file)
+			name := filepath.Clean(event.Name)
+			written := name == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+			// Removing the overlay falls back to the base file, so it
+			// reloads as well.
+			profileChanged := name == ct.watchedProfile() && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0
+			if written || profileChanged || (currentFile != "" && currentFile != realFile) {
+				realFile = currentFile
+				w.changed()
+			}
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			ct.reloadFailed(err)
+		}
+	}
+}
+
+// watchedProfile returns the overlay path of the active mode, whether or not
+// the file exists yet, so creating it triggers a reload too.
+func (ct *Config) watchedProfile() string {
+	ct.mu.RLock()
+	mode := ct.Mode
+	ct.mu.RUnlock()
+	if mode == "" {
+		return ""
+	}
+	return filepath.Clean(profilePath(ct.filepath, mode))
+}
+
+func (ct *Config) reloadFailed(err error) {
+	if ct.onReloadError != nil {
+		ct.onReloadError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "reload config %s: %v\n", ct.filepath, err)
+}
+
+func notifySubscribers(subscribers []subscriber, old, next core.Config) {
+	if len(subscribers) == 0 {
+		return
+	}
+	changed := changedSections(old, next)
+	if len(changed) == 0 {
+		return
+	}
+	for _, sub := range subscribers {
+		if sub.section == "" || changed[sub.section] {
+			sub.fn(old, next)
+		}
+	}
+}
+
+// changedSections compares the top-level fields of two snapshots and
+// returns the mapstructure keys of those that differ.
+func changedSections(old, next core.Config) map[string]bool {
+	oldValue := reflect.ValueOf(old)
+	nextValue := reflect.ValueOf(next)
+	changed := make(map[string]bool)
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			changed[name] = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// newJournaldSink sends entries to journald over its native protocol, with
+// PRIORITY, SYSLOG_IDENTIFIER and the caller as journal fields. Address
+// overrides the socket path.
+func newJournaldSink(sink core.LoggerSinkConfig, _ core.IConfig) (zapcore.Core, io.Closer, error) {
+	socket := strings.TrimSpace(sink.Address)
+	if socket == "" {
+		socket = defaultJournaldSocket
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return nil, nil, cerrs.Wrap(err, fmt.Sprintf("dialing journald error,socket:%s", socket))
+	}
+	tag := strings.TrimSpace(sink.Tag)
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	sinkCore := &lineCore{enc: sinkEncoder(sink), write: func(entry zapcore.Entry, line string) error {
+		var msg bytes.Buffer
+		writeJournalField(&msg, "MESSAGE", line)
+		writeJournalField(&msg, "PRIORITY", strconv.Itoa(syslogPriority(entry.Level)))
+		writeJournalField(&msg, "SYSLOG_IDENTIFIER", tag)
+		if entry.Caller.Defined {
+			writeJournalField(&msg, "CODE_FILE", entry.Caller.File)
+			writeJournalField(&msg, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		}
+		_, err := conn.Write(msg.Bytes())
+		return err
+	}}
+	return sinkCore, conn, nil
+}
+
+// writeJournalField appends one field in the journal export format; values
+// with newlines use the length-prefixed binary form.
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
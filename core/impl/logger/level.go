@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels holds the root level and the per-module overrides shared by a
+// logger and every module logger derived from it.
+type levels struct {
+	root zap.AtomicLevel
+
+	mu      sync.RWMutex
+	modules map[string]zapcore.Level
+}
+
+func newLevels(root zapcore.Level) *levels {
+	return &levels{root: zap.NewAtomicLevelAt(root), modules: make(map[string]zapcore.Level)}
+}
+
+// enabled checks level against the override of module or of its closest
+// parent ("rpcclient" for "rpcclient.consul"), falling back to the root level.
+func (ls *levels) enabled(module string, level zapcore.Level) bool {
+	if module != "" {
+		ls.mu.RLock()
+		for name := module; name != ""; name = parentModule(name) {
+			if min, ok := ls.modules[name]; ok {
+				ls.mu.RUnlock()
+				return level >= min
+			}
+		}
+		ls.mu.RUnlock()
+	}
+	return ls.root.Enabled(level)
+}
+
+func parentModule(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func (ls *levels) setModule(module string, level zapcore.Level) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.modules[module] = level
+}
+
+func (ls *levels) resetModule(module string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	delete(ls.modules, module)
+}
+
+// apply replaces the root level and every override, as on config reload.
+func (ls *levels) apply(root int, modules map[string]int) error {
+	rootLevel, err := parseLevel(root)
+	if err != nil {
+		return err
+	}
+	next := make(map[string]zapcore.Level, len(modules))
+	for module, value := range modules {
+		level, err := parseLevel(value)
+		if err != nil {
+			return cerrs.Wrap(err, fmt.Sprintf("logger module %s", module))
+		}
+		next[strings.ToLower(module)] = level
+	}
+	ls.root.SetLevel(rootLevel)
+	ls.mu.Lock()
+	ls.modules = next
+	ls.mu.Unlock()
+	return nil
+}
+
+func (ls *levels) snapshot() levelPayload {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	modules := make(map[string]string, len(ls.modules))
+	for module, level := range ls.modules {
+		modules[module] = level.String()
+	}
+	return levelPayload{Level: ls.root.Level().String(), Modules: modules}
+}
+
+// leveledCore filters entries by the level of one module before the wrapped
+// cores, which only split entries between outputs.
+type leveledCore struct {
+	zapcore.Core
+	levels *levels
+	module string
+}
+
+func (c *leveledCore) Enabled(level zapcore.Level) bool {
+	return c.levels.enabled(c.module, level)
+}
+
+func (c *leveledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &leveledCore{Core: c.Core.With(fields), levels: c.levels, module: c.module}
+}
+
+func (c *leveledCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+type levelPayload struct {
+	Module  string            `json:"module,omitempty"`
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LevelHandler serves the levels of the logger at runtime:
+//
+//	GET  returns {"level":"info","modules":{"rpcclient":"debug"}}
+//	PUT  {"level":"debug"} sets the root level
+//	PUT  {"module":"rpcclient","level":"debug"} overrides one module
+//	PUT  {"module":"rpcclient","level":""} removes the override
+//
+// Changes last until the next config reload of the logger section.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(l.serveLevel)
+}
+
+func (l *Logger) serveLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var payload levelPayload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&payload); err != nil {
+			writeLevelError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		module := strings.ToLower(strings.TrimSpace(payload.Module))
+		if module != "" && strings.TrimSpace(payload.Level) == "" {
+			l.levels.resetModule(module)
+			break
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(payload.Level))); err != nil {
+			writeLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if module == "" {
+			l.levels.root.SetLevel(level)
+		} else {
+			l.levels.setModule(module, level)
+		}
+		l.Info("logger level changed", zap.String("module", module), zap.String("level", level.String()))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l.levels.snapshot())
+}
+
+func writeLevelError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
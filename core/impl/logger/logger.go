@@ -3,22 +3,34 @@ package logger
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
 
-	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type Logger struct {
-	logger *zap.Logger
-	conf   core.IConfig
+	logger   *zap.Logger
+	conf     core.IConfig
+	levels   *levels
+	throttle *throttle
+	module   string
+	closers  []io.Closer
+
+	// mu guards fields, which AddGlobalFields may extend while other
+	// goroutines log.
+	mu     sync.RWMutex
 	fields []zap.Field
 }
 
+var _ core.IModuleLogger = (*Logger)(nil)
+
 func NewLogger(config core.IConfig) (*Logger, error) {
 	logger := &Logger{
 		conf: config,
@@ -59,70 +71,146 @@ func (l *Logger) Panic(msg string, fields ...any) {
 	l.withFields().Panic(msg, l.convertFields(fields...)...)
 }
 
-func (l *Logger) AddGlobalFields(fields ...any) {
-	for _, field := range fields {
-		if f, ok := field.(zap.Field); ok {
-			l.fields = append(l.fields, f)
-		}
+// Module returns a logger named after module whose level follows
+// logger.modules.<module>, or the root level without an override. Module
+// names nest with dots, so Module("consul") of the rpcclient logger is
+// tuned by "rpcclient.consul" and then "rpcclient".
+func (l *Logger) Module(name string) core.ILogger {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return l
+	}
+	module := name
+	if l.module != "" {
+		module = l.module + "." + name
+	}
+	return &Logger{
+		logger: l.logger.Named(name).WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			if leveled, ok := c.(*leveledCore); ok {
+				return &leveledCore{Core: leveled.Core, levels: leveled.levels, module: module}
+			}
+			return c
+		})),
+		conf:     l.conf,
+		fields:   l.globalFields(),
+		levels:   l.levels,
+		throttle: l.throttle,
+		module:   module,
 	}
 }
 
-func (l *Logger) init() error {
-	fileEncoder := getFileEncoder()
+// With returns a child logger carrying fields on every entry. The child
+// shares the outputs and levels of l; fields added to either logger later
+// do not affect the other.
+func (l *Logger) With(fields ...any) core.ILogger {
+	return &Logger{
+		logger:   l.withFields().With(l.convertFields(fields...)...),
+		conf:     l.conf,
+		levels:   l.levels,
+		throttle: l.throttle,
+		module:   l.module,
+	}
+}
+
+// Sync flushes buffered output, including pending "suppressed N similar
+// messages" summaries. Call it before the process exits.
+func (l *Logger) Sync() error {
+	return l.logger.Sync()
+}
 
+// Close flushes pending entries and releases the outputs, such as rotated
+// files and the OTLP exporter. Module and child loggers share the outputs,
+// so close only the logger returned by NewLogger, after the last entry.
+func (l *Logger) Close() error {
+	// Syncing stdout fails with EINVAL on terminals and pipes; the entries
+	// are written either way, so only closing errors are reported.
+	_ = l.Sync()
+	if l.throttle != nil {
+		l.throttle.stop()
+	}
+	return closeAll(l.closers)
+}
+
+// AddGlobalFields accepts the same arguments as the logging methods:
+// key-value pairs, slog.Attr and zap.Field.
+func (l *Logger) AddGlobalFields(fields ...any) {
+	converted := l.convertFields(fields...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fields = append(l.fields, converted...)
+}
+
+func (l *Logger) globalFields() []zap.Field {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]zap.Field(nil), l.fields...)
+}
+
+func (l *Logger) init() error {
 	if l.conf == nil {
 		return cerrs.New("logger config not found")
 	}
-	coreArr := []zapcore.Core{
-		zapcore.NewCore(fileEncoder, getStdoutWriter(), zap.DebugLevel),
+	loggerConf := l.conf.GetLogger()
+	l.levels = newLevels(zapcore.InfoLevel)
+	if err := l.levels.apply(loggerConf.Level, loggerConf.Modules); err != nil {
+		return err
 	}
-	if l.conf.GetLogger().FilePath != "" {
-		infoWriter, err := getInfoLogFileWriter(l.conf)
-		if err != nil {
-			return err
-		}
-		errWriter, err := getErrLogFileWriter(l.conf)
-		if err != nil {
-			return err
-		}
-
-		errLevelEnabler := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-			return level >= zap.ErrorLevel
-		})
-		infoLevelEnabler := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-			return level < zap.ErrorLevel
+	redactorRef := &atomic.Pointer[redactor]{}
+	initial, err := newRedactor(loggerConf.Redact)
+	if err != nil {
+		return err
+	}
+	redactorRef.Store(initial)
+	if watcher, ok := l.conf.(core.IConfigWatcher); ok {
+		watcher.Subscribe("logger", func(_, next core.Config) {
+			if err := l.levels.apply(next.Logger.Level, next.Logger.Modules); err != nil {
+				l.Error("apply logger levels error", zap.Error(err))
+			}
+			l.throttle.configure(newThrottleSettings(next.Logger.Sampling, next.Logger.RateLimit))
+			if r, err := newRedactor(next.Logger.Redact); err != nil {
+				l.Error("apply logger redaction error", zap.Error(err))
+			} else {
+				redactorRef.Store(r)
+			}
 		})
-		coreArr = append(coreArr,
-			zapcore.NewCore(fileEncoder, infoWriter, infoLevelEnabler),
-			zapcore.NewCore(fileEncoder, errWriter, errLevelEnabler),
-		)
 	}
 
-	l.logger = zap.New(zapcore.NewTee(coreArr...), zap.AddCaller(), zap.AddCallerSkip(1))
+	// Levels are checked by leveledCore; the sink cores only apply their
+	// own minimum level and route entries to their outputs.
+	cores, closers, err := buildSinks(l.conf)
+	if err != nil {
+		return err
+	}
+	l.closers = closers
+
+	// Redaction sits below the throttle so suppression summaries, which
+	// quote the message, are masked as well.
+	masked := &redactCore{Core: zapcore.NewTee(cores...), redactor: redactorRef}
+	l.throttle = newThrottle(masked, newThrottleSettings(loggerConf.Sampling, loggerConf.RateLimit))
+	throttled := &throttleCore{Core: masked, throttle: l.throttle}
+	l.logger = zap.New(&leveledCore{Core: throttled, levels: l.levels}, zap.AddCaller(), zap.AddCallerSkip(1))
 	return nil
 }
 
+// parseLevel maps LoggerConfig.Level onto zap levels: -1 debug, 0 info,
+// 1 warn, 2 error, 3 dpanic, 4 panic, 5 fatal.
+func parseLevel(value int) (zapcore.Level, error) {
+	level := zapcore.Level(value)
+	if level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+		return level, cerrs.New(fmt.Sprintf("unsupported logger level %d", value))
+	}
+	return level, nil
+}
+
 func (l *Logger) withFields() *zap.Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	if len(l.fields) > 0 {
 		return l.logger.With(l.fields...)
 	}
 	return l.logger
 }
 
-func (l *Logger) convertFields(fields ...any) []zap.Field {
-	zapFields := make([]zap.Field, 0, len(fields))
-	for _, field := range fields {
-		if f, ok := field.(zap.Field); ok {
-			zapFields = append(zapFields, f)
-		} else if e, ok := field.(error); ok {
-			zapFields = append(zapFields, zap.Error(e))
-		} else {
-			zapFields = append(zapFields, zap.Any("field", field))
-		}
-	}
-	return zapFields
-}
-
 func getFileEncoder() zapcore.Encoder {
 	encodeConfig := zap.NewProductionEncoderConfig()
 	encodeConfig.EncodeTime = zapcore.ISO8601TimeEncoder
@@ -131,34 +219,3 @@ func getFileEncoder() zapcore.Encoder {
 	encodeConfig.EncodeCaller = zapcore.FullCallerEncoder
 	return zapcore.NewJSONEncoder(encodeConfig)
 }
-
-func getLogFileConfig(conf core.IConfig, filename string) (*lumberjack.Logger, error) {
-	loggerConf := conf.GetLogger()
-	return &lumberjack.Logger{
-		Filename:   loggerConf.FilePath + "/" + filename,
-		MaxSize:    loggerConf.MaxSize,
-		MaxAge:     loggerConf.MaxAge,
-		MaxBackups: loggerConf.MaxBackups,
-		Compress:   false,
-	}, nil
-}
-
-func getErrLogFileWriter(conf core.IConfig) (zapcore.WriteSyncer, error) {
-	lumberJackLogger, err := getLogFileConfig(conf, "error.log")
-	if err != nil {
-		return nil, err
-	}
-	return zapcore.AddSync(lumberJackLogger), nil
-}
-
-func getInfoLogFileWriter(conf core.IConfig) (zapcore.WriteSyncer, error) {
-	lumberJackLogger, err := getLogFileConfig(conf, "info.log")
-	if err != nil {
-		return nil, err
-	}
-	return zapcore.AddSync(lumberJackLogger), nil
-}
-
-func getStdoutWriter() zapcore.WriteSyncer {
-	return zapcore.AddSync(os.Stdout)
-}
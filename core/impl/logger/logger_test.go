@@ -2,12 +2,19 @@ package logger
 
 import (
 	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/iconnor-code/cogo/core"
 	configimpl "github.com/iconnor-code/cogo/core/impl/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestLoggerWritesToStdoutWithoutFilePath(t *testing.T) {
@@ -45,3 +52,132 @@ func TestLoggerWritesToStdoutWithoutFilePath(t *testing.T) {
 		t.Fatalf("stdout output = %q, want log message", output)
 	}
 }
+
+func TestLoggerLevelFollowsConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := configimpl.NewConfig(configimpl.WithFilePath(configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewLogger(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logger.levels.root.Enabled(zapcore.InfoLevel) {
+		t.Fatal("info enabled at warn level")
+	}
+
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: -1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.levels.root.Enabled(zapcore.DebugLevel) {
+		t.Fatalf("level = %v after reload, want debug", logger.levels.root.Level())
+	}
+}
+
+func TestNewLoggerRejectsUnknownLevel(t *testing.T) {
+	conf := &configimpl.Config{Config: core.Config{Logger: core.LoggerConfig{Level: 9}}}
+	if _, err := NewLogger(conf); err == nil {
+		t.Fatal("NewLogger accepted level 9")
+	}
+}
+
+func TestModuleLevelsFollowConfigAndHandler(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 0\n  modules:\n    rpcclient: -1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := configimpl.NewConfig(configimpl.WithFilePath(configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewLogger(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !logger.levels.enabled("rpcclient.consul", zapcore.DebugLevel) {
+		t.Fatal("debug disabled for rpcclient.consul, want inherited rpcclient override")
+	}
+	if logger.levels.enabled("registry", zapcore.DebugLevel) {
+		t.Fatal("debug enabled for registry without override")
+	}
+
+	handler := logger.LevelHandler()
+	request := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"module":"registry","level":"warn"}`))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	if logger.levels.enabled("registry", zapcore.InfoLevel) {
+		t.Fatal("info enabled for registry after PUT warn")
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	if body := recorder.Body.String(); !strings.Contains(body, `"registry":"warn"`) || !strings.Contains(body, `"level":"info"`) {
+		t.Fatalf("GET body = %s, want root info and registry warn", body)
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"loud"}`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("invalid level status = %d, want 400", recorder.Code)
+	}
+
+	if err := os.WriteFile(configPath, []byte("logger:\n  level: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if logger.levels.enabled("rpcclient", zapcore.InfoLevel) || logger.levels.enabled("registry", zapcore.InfoLevel) {
+		t.Fatal("module overrides survived a reload that removed them")
+	}
+}
+
+func TestWithReturnsChildWithoutMutatingParent(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	levels := newLevels(zapcore.DebugLevel)
+	parent := &Logger{logger: zap.New(&leveledCore{Core: observed, levels: levels}), levels: levels}
+
+	first := parent.With(zap.String("to", "a@test"))
+	second := parent.With(zap.String("to", "b@test"))
+	first.Info("first")
+	second.Info("second")
+	parent.Info("parent")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if got := entries[0].ContextMap()["to"]; got != "a@test" {
+		t.Fatalf("first child to = %v, want a@test", got)
+	}
+	if got := entries[1].ContextMap()["to"]; got != "b@test" {
+		t.Fatalf("second child to = %v, want b@test", got)
+	}
+	if _, ok := entries[2].ContextMap()["to"]; ok {
+		t.Fatalf("parent entry has child field: %v", entries[2].ContextMap())
+	}
+}
+
+func TestAddGlobalFieldsAcceptsKeyValuePairs(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	levels := newLevels(zapcore.DebugLevel)
+	l := &Logger{logger: zap.New(&leveledCore{Core: observed, levels: levels}), levels: levels}
+
+	l.AddGlobalFields("service", "account", slog.Int("pid", 42), zap.String("zone", "a"))
+	l.Info("started")
+
+	fields := logs.AllUntimed()[0].ContextMap()
+	if fields["service"] != "account" || fields["pid"] != int64(42) || fields["zone"] != "a" {
+		t.Fatalf("global fields = %v, want service, pid and zone", fields)
+	}
+}
@@ -0,0 +1,315 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces values under sensitive keys, as config Dump does.
+const redacted = "[REDACTED]"
+
+var (
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// detector masks one kind of sensitive value inside strings. hint is a cheap
+// check that skips the regular expression for most strings.
+type detector struct {
+	hint    func(s string) bool
+	pattern *regexp.Regexp
+	mask    func(match string) string
+}
+
+var detectors = map[string]detector{
+	"jwt": {
+		hint:    func(s string) bool { return strings.Contains(s, "eyJ") },
+		pattern: jwtPattern,
+		mask:    func(string) string { return redacted },
+	},
+	"email": {
+		hint:    func(s string) bool { return strings.Contains(s, "@") },
+		pattern: emailPattern,
+		mask:    maskEmail,
+	},
+	"card": {
+		hint:    hasCardDigits,
+		pattern: cardPattern,
+		mask:    maskCard,
+	},
+}
+
+// redactor masks field values with sensitive keys and sensitive values
+// found by the detectors. A nil redactor leaves entries unchanged.
+type redactor struct {
+	keys      *regexp.Regexp
+	detectors []detector
+}
+
+func newRedactor(conf core.LoggerRedactConfig) (*redactor, error) {
+	if conf.Disable {
+		return nil, nil
+	}
+	keys, err := conf.KeyPattern()
+	if err != nil {
+		return nil, cerrs.Wrap(err, "logger redact key pattern error")
+	}
+	r := &redactor{keys: keys}
+
+	names := conf.Detectors
+	if len(names) == 0 {
+		names = []string{"jwt", "email", "card"}
+	}
+	for _, name := range names {
+		d, ok := detectors[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, cerrs.New(fmt.Sprintf("unsupported logger redact detector %q", name))
+		}
+		r.detectors = append(r.detectors, d)
+	}
+	return r, nil
+}
+
+func (r *redactor) secretKey(key string) bool {
+	return key != "" && r.keys.MatchString(key)
+}
+
+// text masks the detected values in s.
+func (r *redactor) text(s string) string {
+	for _, d := range r.detectors {
+		if d.hint(s) {
+			s = d.pattern.ReplaceAllStringFunc(s, d.mask)
+		}
+	}
+	return s
+}
+
+// fields returns fields with sensitive values masked. The slice is copied
+// only when a field changes.
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	if r == nil {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, field := range fields {
+		masked, changed := r.field(field)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = append(make([]zapcore.Field, 0, len(fields)), fields...)
+		}
+		out[i] = masked
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (r *redactor) field(field zapcore.Field) (zapcore.Field, bool) {
+	switch field.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return field, false
+	}
+	if r.secretKey(field.Key) {
+		return zap.String(field.Key, redacted), true
+	}
+	switch field.Type {
+	case zapcore.StringType:
+		if masked := r.text(field.String); masked != field.String {
+			return zap.String(field.Key, masked), true
+		}
+	case zapcore.StringerType, zapcore.ErrorType:
+		s, ok := describe(field.Interface)
+		if masked := r.text(s); ok && masked != s {
+			return zap.String(field.Key, masked), true
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		value, ok := plainValue(field)
+		if !ok {
+			return field, false
+		}
+		if masked, changed := r.value(value); changed {
+			return zap.Any(field.Key, masked), true
+		}
+	}
+	return field, false
+}
+
+// value masks a decoded JSON value in place.
+func (r *redactor) value(value any) (any, bool) {
+	switch value := value.(type) {
+	case string:
+		masked := r.text(value)
+		return masked, masked != value
+	case map[string]any:
+		changed := false
+		for key, nested := range value {
+			if r.secretKey(key) {
+				if nested != nil {
+					value[key], changed = redacted, true
+				}
+				continue
+			}
+			if masked, ok := r.value(nested); ok {
+				value[key], changed = masked, true
+			}
+		}
+		return value, changed
+	case []any:
+		changed := false
+		for i, nested := range value {
+			if masked, ok := r.value(nested); ok {
+				value[i], changed = masked, true
+			}
+		}
+		return value, changed
+	}
+	return value, false
+}
+
+// describe returns the text of an error or fmt.Stringer, guarding against
+// nil receivers the way zap does when encoding them.
+func describe(v any) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			s, ok = "", false
+		}
+	}()
+	switch v := v.(type) {
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// plainValue renders structured fields as decoded JSON so nested keys and
+// strings can be inspected. Numbers stay json.Number to keep their digits.
+func plainValue(field zapcore.Field) (any, bool) {
+	var value any
+	switch field.Type {
+	case zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := field.Interface.(zapcore.ObjectMarshaler).MarshalLogObject(enc); err != nil {
+			return nil, false
+		}
+		value = enc.Fields
+	case zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := enc.AddArray("array", field.Interface.(zapcore.ArrayMarshaler)); err != nil {
+			return nil, false
+		}
+		value = enc.Fields["array"]
+	default:
+		value = field.Interface
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var plain any
+	if err := decoder.Decode(&plain); err != nil {
+		return nil, false
+	}
+	return plain, true
+}
+
+// maskEmail keeps the first character and the domain: a***@example.com.
+func maskEmail(match string) string {
+	at := strings.LastIndexByte(match, '@')
+	if at <= 0 {
+		return redacted
+	}
+	return match[:1] + "***" + match[at:]
+}
+
+// maskCard keeps the last four digits of numbers that look like payment
+// cards: a known issuer prefix and a valid Luhn checksum. Other long
+// numbers, such as order IDs, are left alone.
+func maskCard(match string) string {
+	digits := make([]byte, 0, len(match))
+	for i := 0; i < len(match); i++ {
+		if match[i] >= '0' && match[i] <= '9' {
+			digits = append(digits, match[i])
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 || !strings.ContainsRune("23456", rune(digits[0])) || !luhn(digits) {
+		return match
+	}
+	return "****" + string(digits[len(digits)-4:])
+}
+
+func luhn(digits []byte) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func hasCardDigits(s string) bool {
+	count := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			if count++; count >= 13 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactCore masks messages and fields before the output cores encode them.
+// The redactor is swapped on config reload; fields attached through With
+// keep the masking they got when attached.
+type redactCore struct {
+	zapcore.Core
+	redactor *atomic.Pointer[redactor]
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactor.Load().fields(fields)), redactor: c.redactor}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write masks the entry and hands it to the output cores through Check, so
+// each output still applies its own level.
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if r := c.redactor.Load(); r != nil {
+		entry.Message = r.text(entry.Message)
+		fields = r.fields(fields)
+	}
+	if checked := c.Core.Check(entry, nil); checked != nil {
+		checked.Write(fields...)
+	}
+	return nil
+}
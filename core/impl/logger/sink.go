@@ -0,0 +1,290 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/tracing"
+	"github.com/natefinch/lumberjack"
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SinkFactory builds the core of one logger.sinks entry. The core should
+// accept every level; the sink level and logger.level are applied around
+// it. A non-nil closer is closed by Logger.Close.
+type SinkFactory func(sink core.LoggerSinkConfig, conf core.IConfig) (zapcore.Core, io.Closer, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{
+		"console":  newConsoleSink,
+		"file":     newFileSink,
+		"syslog":   newSyslogSink,
+		"journald": newJournaldSink,
+		"otlp":     newOTLPSink,
+	}
+)
+
+// RegisterSink makes a custom sink type available to logger.sinks, or
+// replaces a built-in one. Register sinks before calling NewLogger.
+func RegisterSink(name string, factory SinkFactory) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return cerrs.New("sink name is required")
+	}
+	if factory == nil {
+		return cerrs.New(fmt.Sprintf("sink factory is required,name:%s", name))
+	}
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories[name] = factory
+	return nil
+}
+
+func sinkFactory(name string) (SinkFactory, bool) {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+	factory, ok := sinkFactories[strings.ToLower(strings.TrimSpace(name))]
+	return factory, ok
+}
+
+// buildSinks returns the output cores of conf. Without logger.sinks it keeps
+// the default outputs: JSON on stdout, plus info.log and error.log under
+// logger.file_path.
+func buildSinks(conf core.IConfig) ([]zapcore.Core, []io.Closer, error) {
+	loggerConf := conf.GetLogger()
+	if len(loggerConf.Sinks) == 0 {
+		return defaultSinks(loggerConf)
+	}
+
+	cores := make([]zapcore.Core, 0, len(loggerConf.Sinks))
+	var closers []io.Closer
+	fail := func(err error) ([]zapcore.Core, []io.Closer, error) {
+		return nil, nil, errors.Join(err, closeAll(closers))
+	}
+	for i, sink := range loggerConf.Sinks {
+		factory, ok := sinkFactory(sink.Type)
+		if !ok {
+			return fail(cerrs.New(fmt.Sprintf("unsupported logger sink %q,index:%d", sink.Type, i)))
+		}
+		level, err := sinkLevel(sink.Level)
+		if err != nil {
+			return fail(cerrs.Wrap(err, fmt.Sprintf("logger sink level error,index:%d", i)))
+		}
+		sinkCore, closer, err := factory(sink, conf)
+		if err != nil {
+			return fail(cerrs.Wrap(err, fmt.Sprintf("creating logger sink error,type:%s,index:%d", sink.Type, i)))
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+		if level > zapcore.DebugLevel {
+			if sinkCore, err = zapcore.NewIncreaseLevelCore(sinkCore, level); err != nil {
+				return fail(cerrs.Wrap(err, fmt.Sprintf("logger sink level error,index:%d", i)))
+			}
+		}
+		cores = append(cores, sinkCore)
+	}
+	return cores, closers, nil
+}
+
+func defaultSinks(loggerConf core.LoggerConfig) ([]zapcore.Core, []io.Closer, error) {
+	encoder := getFileEncoder()
+	cores := []zapcore.Core{
+		zapcore.NewCore(encoder, getStdoutWriter(), zapcore.DebugLevel),
+	}
+	if loggerConf.FilePath == "" {
+		return cores, nil, nil
+	}
+	infoFile := getLogFileConfig(loggerConf, "info.log")
+	errFile := getLogFileConfig(loggerConf, "error.log")
+	errLevelEnabler := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level >= zap.ErrorLevel
+	})
+	infoLevelEnabler := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return level < zap.ErrorLevel
+	})
+	cores = append(cores,
+		zapcore.NewCore(encoder, zapcore.AddSync(infoFile), infoLevelEnabler),
+		zapcore.NewCore(encoder, zapcore.AddSync(errFile), errLevelEnabler),
+	)
+	return cores, []io.Closer{infoFile, errFile}, nil
+}
+
+func sinkLevel(name string) (zapcore.Level, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return zapcore.DebugLevel, nil
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, cerrs.Wrap(err)
+	}
+	return level, nil
+}
+
+// sinkEncoder returns the JSON encoder of the default outputs, or a
+// human-readable one for format text.
+func sinkEncoder(sink core.LoggerSinkConfig) zapcore.Encoder {
+	if !strings.EqualFold(strings.TrimSpace(sink.Format), "text") {
+		return getFileEncoder()
+	}
+	encodeConfig := zap.NewDevelopmentEncoderConfig()
+	encodeConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.000")
+	encodeConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	encodeConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	if sink.Color {
+		encodeConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	return zapcore.NewConsoleEncoder(encodeConfig)
+}
+
+func newConsoleSink(sink core.LoggerSinkConfig, _ core.IConfig) (zapcore.Core, io.Closer, error) {
+	return zapcore.NewCore(sinkEncoder(sink), getStdoutWriter(), zapcore.DebugLevel), nil, nil
+}
+
+func newFileSink(sink core.LoggerSinkConfig, _ core.IConfig) (zapcore.Core, io.Closer, error) {
+	if strings.TrimSpace(sink.Path) == "" {
+		return nil, nil, cerrs.New("file sink path is required")
+	}
+	file := &lumberjack.Logger{
+		Filename:   sink.Path,
+		MaxSize:    sink.MaxSize,
+		MaxAge:     sink.MaxAge,
+		MaxBackups: sink.MaxBackups,
+		Compress:   sink.Compress,
+	}
+	return zapcore.NewCore(sinkEncoder(sink), zapcore.AddSync(file), zapcore.DebugLevel), file, nil
+}
+
+// otlpShutdownTimeout bounds the final export of buffered records on Close.
+const otlpShutdownTimeout = 5 * time.Second
+
+// newOTLPSink exports records over OTLP/gRPC with the service.name used for
+// traces, so logs and spans of a service correlate in the backend.
+func newOTLPSink(sink core.LoggerSinkConfig, conf core.IConfig) (zapcore.Core, io.Closer, error) {
+	if strings.TrimSpace(sink.Endpoint) == "" {
+		return nil, nil, cerrs.New("otlp sink endpoint is required")
+	}
+	opts := []otlploggrpc.Option{otlploggrpc.WithEndpoint(sink.Endpoint)}
+	if sink.Insecure {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	}
+	if len(sink.Headers) > 0 {
+		opts = append(opts, otlploggrpc.WithHeaders(sink.Headers))
+	}
+	exporter, err := otlploggrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, nil, cerrs.Wrap(err, "creating otlp log exporter error")
+	}
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracing.ServiceName(conf)),
+		)),
+	)
+	closer := closerFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	})
+	return otelzap.NewCore("github.com/iconnor-code/cogo", otelzap.WithLoggerProvider(provider)), closer, nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// lineCore encodes each entry into one line handed to write, for outputs
+// that take whole messages with a priority, such as syslog and journald.
+type lineCore struct {
+	enc   zapcore.Encoder
+	write func(entry zapcore.Entry, line string) error
+}
+
+func (c *lineCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *lineCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	return &lineCore{enc: enc, write: c.write}
+}
+
+func (c *lineCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *lineCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimRight(buf.String(), "\n")
+	buf.Free()
+	return c.write(entry, line)
+}
+
+func (c *lineCore) Sync() error {
+	return nil
+}
+
+// syslogPriority maps zap levels onto syslog severities.
+func syslogPriority(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	}
+	return 1
+}
+
+func getLogFileConfig(loggerConf core.LoggerConfig, filename string) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   loggerConf.FilePath + "/" + filename,
+		MaxSize:    loggerConf.MaxSize,
+		MaxAge:     loggerConf.MaxAge,
+		MaxBackups: loggerConf.MaxBackups,
+		Compress:   loggerConf.Compress,
+	}
+}
+
+func getStdoutWriter() zapcore.WriteSyncer {
+	return zapcore.AddSync(os.Stdout)
+}
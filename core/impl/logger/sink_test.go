@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	configimpl "github.com/iconnor-code/cogo/core/impl/config"
+	"go.uber.org/zap/zapcore"
+)
+
+func newSinkLogger(t *testing.T, sinks ...core.LoggerSinkConfig) *Logger {
+	t.Helper()
+	conf := &configimpl.Config{Config: core.Config{Logger: core.LoggerConfig{Level: -1, Sinks: sinks}}}
+	logger, err := NewLogger(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestFileSinksApplyTheirOwnLevelAndFormat(t *testing.T) {
+	dir := t.TempDir()
+	allPath := filepath.Join(dir, "all.log")
+	errPath := filepath.Join(dir, "error.log")
+	logger := newSinkLogger(t,
+		core.LoggerSinkConfig{Type: "file", Path: allPath, Format: "text"},
+		core.LoggerSinkConfig{Type: "file", Path: errPath, Level: "error"},
+	)
+	logger.Debug("cache miss", "key", "user:7")
+	logger.Error("query failed", "table", "users")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := os.ReadFile(allPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(all), "DEBUG") || !strings.Contains(string(all), "cache miss") || strings.Contains(string(all), `"msg"`) {
+		t.Fatalf("text sink = %q, want human-readable debug and error lines", all)
+	}
+	errs, err := os.ReadFile(errPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(errs), "cache miss") || !strings.Contains(string(errs), `"msg":"query failed"`) {
+		t.Fatalf("error sink = %q, want only the JSON error line", errs)
+	}
+}
+
+func TestRegisteredSinkAndUnknownSink(t *testing.T) {
+	var got []string
+	err := RegisterSink("memory", func(core.LoggerSinkConfig, core.IConfig) (zapcore.Core, io.Closer, error) {
+		return &lineCore{enc: getFileEncoder(), write: func(_ zapcore.Entry, line string) error {
+			got = append(got, line)
+			return nil
+		}}, nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := newSinkLogger(t, core.LoggerSinkConfig{Type: "memory", Level: "warn"})
+	logger.Info("ignored")
+	logger.Warn("kept")
+	if len(got) != 1 || !strings.Contains(got[0], `"msg":"kept"`) {
+		t.Fatalf("memory sink lines = %q, want the warn entry", got)
+	}
+
+	conf := &configimpl.Config{Config: core.Config{Logger: core.LoggerConfig{Sinks: []core.LoggerSinkConfig{{Type: "kafka"}}}}}
+	if _, err := NewLogger(conf); err == nil || !strings.Contains(err.Error(), "kafka") {
+		t.Fatalf("NewLogger error = %v, want unsupported sink", err)
+	}
+}
+
+func TestJournaldSinkSendsNativeFields(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram not available: %v", err)
+	}
+	defer listener.Close()
+
+	logger := newSinkLogger(t, core.LoggerSinkConfig{Type: "journald", Address: socket, Tag: "account", Format: "text"})
+	logger.Warn("consul unreachable")
+
+	buf := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	datagram := string(buf[:n])
+	for _, want := range []string{"PRIORITY=4\n", "SYSLOG_IDENTIFIER=account\n", "consul unreachable", "CODE_FILE="} {
+		if !strings.Contains(datagram, want) {
+			t.Fatalf("journal datagram %q misses %q", datagram, want)
+		}
+	}
+}
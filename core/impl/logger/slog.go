@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// badKey names values without a key, as slog does.
+const badKey = "!BADKEY"
+
+// convertFields turns the arguments of the logging methods into zap fields
+// the way slog treats its arguments: a string followed by a value is a
+// key/value pair and slog.Attr values are used as is. zap.Field values are
+// still accepted, and a bare error becomes the "error" field.
+func (l *Logger) convertFields(args ...any) []zap.Field {
+	fields := make([]zap.Field, 0, len(args))
+	for len(args) > 0 {
+		switch arg := args[0].(type) {
+		case zap.Field:
+			fields = append(fields, arg)
+			args = args[1:]
+		case slog.Attr:
+			fields = appendAttr(fields, arg)
+			args = args[1:]
+		case error:
+			fields = append(fields, zap.Error(arg))
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				fields = append(fields, zap.String(badKey, arg))
+				args = args[1:]
+				continue
+			}
+			fields = appendAttr(fields, slog.Any(arg, args[1]))
+			args = args[2:]
+		default:
+			fields = append(fields, zap.Any(badKey, arg))
+			args = args[1:]
+		}
+	}
+	return fields
+}
+
+// appendAttr converts one slog attribute, resolving LogValuers and nesting
+// groups. Empty attributes and empty groups are dropped as slog handlers do.
+func appendAttr(fields []zap.Field, attr slog.Attr) []zap.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	value := attr.Value
+	switch value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, value.Time()))
+	case slog.KindGroup:
+		var group []zap.Field
+		for _, member := range value.Group() {
+			group = appendAttr(group, member)
+		}
+		if len(group) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			return append(fields, group...)
+		}
+		return append(fields, zap.Dict(attr.Key, group...))
+	}
+	if err, ok := value.Any().(error); ok {
+		return append(fields, zap.NamedError(attr.Key, err))
+	}
+	return append(fields, zap.Any(attr.Key, value.Any()))
+}
+
+// SlogHandler returns a slog.Handler writing through l, so services can log
+// with log/slog and still get the levels, sampling, fields and outputs of
+// the cogo logger:
+//
+//	slog.SetDefault(slog.New(logger.SlogHandler()))
+func (l *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{core: l.withFields().Core()}
+}
+
+type slogHandler struct {
+	core zapcore.Core
+	// groups opened by WithGroup without attributes yet; slog omits empty
+	// groups, so they are only applied once an attribute arrives.
+	groups []string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevel(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	entry := zapcore.Entry{
+		Level:   zapLevel(record.Level),
+		Time:    record.Time,
+		Message: record.Message,
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		entry.Caller.Function = frame.Function
+	}
+	checked := h.core.Check(entry, nil)
+	if checked == nil {
+		return nil
+	}
+	fields := make([]zap.Field, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendAttr(fields, attr)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = h.nest(fields)
+	}
+	checked.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zap.Field
+	for _, attr := range attrs {
+		fields = appendAttr(fields, attr)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return &slogHandler{core: h.core.With(h.nest(fields))}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{core: h.core, groups: append(append([]string(nil), h.groups...), name)}
+}
+
+// nest places fields inside the pending groups.
+func (h *slogHandler) nest(fields []zap.Field) []zap.Field {
+	if len(h.groups) == 0 {
+		return fields
+	}
+	nested := make([]zap.Field, 0, len(h.groups)+len(fields))
+	for _, group := range h.groups {
+		nested = append(nested, zap.Namespace(group))
+	}
+	return append(nested, fields...)
+}
+
+// zapLevel maps slog levels onto zap, rounding custom levels down.
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	}
+	return zapcore.DebugLevel
+}
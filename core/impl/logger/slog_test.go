@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedLogger(level zapcore.Level) (*Logger, *observer.ObservedLogs) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	levels := newLevels(level)
+	return &Logger{logger: zap.New(&leveledCore{Core: observed, levels: levels}), levels: levels}, logs
+}
+
+func TestKeyValueFieldsFollowSlogConventions(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.DebugLevel)
+	logger.Info("login",
+		"user_id", 7,
+		slog.String("method", "password"),
+		zap.Bool("admin", true),
+		errors.New("boom"),
+		"took", time.Second,
+		slog.Group("client", "ip", "10.0.0.1"),
+		42,
+		"dangling",
+	)
+
+	fields := logs.All()[0].ContextMap()
+	want := map[string]any{
+		"user_id": int64(7),
+		"method":  "password",
+		"admin":   true,
+		"error":   "boom",
+		"took":    time.Second,
+	}
+	for key, value := range want {
+		if got := fields[key]; got != value {
+			t.Fatalf("%s = %#v, want %#v", key, got, value)
+		}
+	}
+	if client, ok := fields["client"].(map[string]any); !ok || client["ip"] != "10.0.0.1" {
+		t.Fatalf("client = %#v, want group with ip", fields["client"])
+	}
+	if _, ok := fields["field"]; ok {
+		t.Fatalf("fields %v still use the generic field key", fields)
+	}
+	if fields[badKey] != "dangling" {
+		t.Fatalf("%s = %v, want the last unpaired value", badKey, fields[badKey])
+	}
+}
+
+func TestSlogHandlerWritesThroughLogger(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.InfoLevel)
+	log := slog.New(logger.SlogHandler()).With("service", "account").WithGroup("req")
+
+	log.Debug("hidden")
+	log.Warn("slow request", "took", 2*time.Second)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want only the warn entry", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != zapcore.WarnLevel || entry.Message != "slow request" || entry.Caller.File == "" {
+		t.Fatalf("entry = %+v, want warn with caller", entry.Entry)
+	}
+	fields := entry.ContextMap()
+	if fields["service"] != "account" {
+		t.Fatalf("service = %v, want account", fields["service"])
+	}
+	req, ok := fields["req"].(map[string]any)
+	if !ok || req["took"] != 2*time.Second {
+		t.Fatalf("req group = %#v, want took inside", fields["req"])
+	}
+}
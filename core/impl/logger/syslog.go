@@ -0,0 +1,57 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"strings"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap/zapcore"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL,
+	"daemon": syslog.LOG_DAEMON, "auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG,
+	"lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS, "uucp": syslog.LOG_UUCP,
+	"cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3, "local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// newSyslogSink writes to the local syslog daemon, or to network/address
+// when set, with the severity of each entry. Facility defaults to user and
+// tag to the program name.
+func newSyslogSink(sink core.LoggerSinkConfig, _ core.IConfig) (zapcore.Core, io.Closer, error) {
+	facility := syslog.LOG_USER
+	if name := strings.ToLower(strings.TrimSpace(sink.Facility)); name != "" {
+		var ok bool
+		if facility, ok = syslogFacilities[name]; !ok {
+			return nil, nil, cerrs.New(fmt.Sprintf("unsupported syslog facility %q", sink.Facility))
+		}
+	}
+	writer, err := syslog.Dial(sink.Network, sink.Address, facility|syslog.LOG_INFO, sink.Tag)
+	if err != nil {
+		return nil, nil, cerrs.Wrap(err, fmt.Sprintf("dialing syslog error,address:%s", sink.Address))
+	}
+	sinkCore := &lineCore{enc: sinkEncoder(sink), write: func(entry zapcore.Entry, line string) error {
+		switch syslogPriority(entry.Level) {
+		case 7:
+			return writer.Debug(line)
+		case 6:
+			return writer.Info(line)
+		case 4:
+			return writer.Warning(line)
+		case 3:
+			return writer.Err(line)
+		case 2:
+			return writer.Crit(line)
+		}
+		return writer.Alert(line)
+	}}
+	return sinkCore, writer, nil
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"io"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogSink(core.LoggerSinkConfig, core.IConfig) (zapcore.Core, io.Closer, error) {
+	return nil, nil, cerrs.New("syslog sink is not supported on this platform")
+}
@@ -0,0 +1,314 @@
+package logger
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSamplingTick    = time.Second
+	defaultRateLimitWindow = time.Minute
+	// maxThrottleKeys bounds the tracked messages; messages built with
+	// fmt.Sprintf would otherwise grow the table without limit.
+	maxThrottleKeys = 4096
+	// throttleShards is the number of independently locked counter tables.
+	throttleShards = 16
+)
+
+type throttleKey struct {
+	level   zapcore.Level
+	message string
+}
+
+type throttleCounter struct {
+	tickStart   time.Time
+	tickCount   int
+	windowStart time.Time
+	windowCount int
+
+	suppressed      int
+	suppressedSince time.Time
+}
+
+type throttleSettings struct {
+	initial    int
+	thereafter int
+	tick       time.Duration
+	limit      int
+	window     time.Duration
+}
+
+func (s throttleSettings) enabled() bool {
+	return s.initial > 0 || s.limit > 0
+}
+
+// summaryInterval is how long drops of one message accumulate before the
+// "suppressed N similar messages" entry is written.
+func (s throttleSettings) summaryInterval() time.Duration {
+	if s.limit > 0 {
+		return s.window
+	}
+	return s.tick
+}
+
+func newThrottleSettings(sampling core.LoggerSamplingConfig, rateLimit core.LoggerRateLimitConfig) throttleSettings {
+	settings := throttleSettings{
+		initial:    sampling.Initial,
+		thereafter: sampling.Thereafter,
+		tick:       sampling.Tick.Duration(),
+		limit:      rateLimit.Limit,
+		window:     rateLimit.Window.Duration(),
+	}
+	if settings.tick <= 0 {
+		settings.tick = defaultSamplingTick
+	}
+	if settings.window <= 0 {
+		settings.window = defaultRateLimitWindow
+	}
+	return settings
+}
+
+// throttleSummary is a pending "suppressed N similar messages" entry. It is
+// written after the shard lock is released.
+type throttleSummary struct {
+	key        throttleKey
+	suppressed int
+	since      time.Time
+	at         time.Time
+}
+
+func (c *throttleCounter) summarize(key throttleKey, now time.Time) throttleSummary {
+	summary := throttleSummary{key: key, suppressed: c.suppressed, since: c.suppressedSince, at: now}
+	c.suppressed = 0
+	return summary
+}
+
+// throttleShard holds the counters of the messages hashed to it, so
+// entries of different messages rarely wait for each other.
+type throttleShard struct {
+	mu       sync.Mutex
+	counters map[throttleKey]*throttleCounter
+}
+
+// throttle drops repeated entries of the same level and message and reports
+// how many were dropped. It is shared by every core derived through With so
+// child loggers count against the same budget. A timer writes the summary
+// of a message once its interval has passed, even if it is not logged
+// again.
+type throttle struct {
+	out  zapcore.Core
+	now  func() time.Time
+	seed maphash.Seed
+
+	settings atomic.Pointer[throttleSettings]
+	shards   [throttleShards]throttleShard
+
+	timerMu sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+func newThrottle(out zapcore.Core, settings throttleSettings) *throttle {
+	t := &throttle{out: out, now: time.Now, seed: maphash.MakeSeed()}
+	t.settings.Store(&settings)
+	for i := range t.shards {
+		t.shards[i].counters = make(map[throttleKey]*throttleCounter)
+	}
+	return t
+}
+
+// configure applies new settings, as on config reload. Pending summaries are
+// written first so no drop goes unreported.
+func (t *throttle) configure(settings throttleSettings) {
+	t.flush(true)
+	t.settings.Store(&settings)
+}
+
+// allow reports whether entry should be written, writing the summary of
+// earlier drops of the same message when its interval has passed.
+func (t *throttle) allow(entry zapcore.Entry) bool {
+	if entry.Level >= zapcore.DPanicLevel {
+		return true
+	}
+	settings := t.settings.Load()
+	if !settings.enabled() {
+		return true
+	}
+	key := throttleKey{level: entry.Level, message: entry.Message}
+	shard := &t.shards[(maphash.String(t.seed, key.message)+uint64(key.level))%throttleShards]
+	shard.mu.Lock()
+	allowed, firstDrop, summaries := shard.allow(key, settings, t.now())
+	shard.mu.Unlock()
+	t.write(summaries)
+	if firstDrop {
+		t.schedule(settings.summaryInterval())
+	}
+	return allowed
+}
+
+// allow applies the sampling and rate limit to key. firstDrop reports a
+// drop of a message that had none pending, which needs a summary later.
+func (s *throttleShard) allow(key throttleKey, settings *throttleSettings, now time.Time) (allowed, firstDrop bool, summaries []throttleSummary) {
+	counter, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= maxThrottleKeys/throttleShards {
+			summaries, _ = s.flush(settings, now, false)
+			if len(s.counters) >= maxThrottleKeys/throttleShards {
+				return true, false, summaries
+			}
+		}
+		counter = &throttleCounter{tickStart: now, windowStart: now}
+		s.counters[key] = counter
+	}
+	if counter.suppressed > 0 && now.Sub(counter.suppressedSince) >= settings.summaryInterval() {
+		summaries = append(summaries, counter.summarize(key, now))
+	}
+
+	allowed = true
+	if settings.initial > 0 {
+		if now.Sub(counter.tickStart) >= settings.tick {
+			counter.tickStart, counter.tickCount = now, 0
+		}
+		counter.tickCount++
+		if over := counter.tickCount - settings.initial; over > 0 {
+			allowed = settings.thereafter > 0 && over%settings.thereafter == 0
+		}
+	}
+	if allowed && settings.limit > 0 {
+		if now.Sub(counter.windowStart) >= settings.window {
+			counter.windowStart, counter.windowCount = now, 0
+		}
+		if counter.windowCount >= settings.limit {
+			allowed = false
+		} else {
+			counter.windowCount++
+		}
+	}
+	if !allowed {
+		if counter.suppressed == 0 {
+			counter.suppressedSince = now
+			firstDrop = true
+		}
+		counter.suppressed++
+	}
+	return allowed, firstDrop, summaries
+}
+
+// flush returns the due summaries and forgets idle messages; all takes
+// every pending summary regardless of its interval. next is the time until
+// the earliest summary left pending is due, 0 when none is left.
+func (s *throttleShard) flush(settings *throttleSettings, now time.Time, all bool) (summaries []throttleSummary, next time.Duration) {
+	interval := settings.summaryInterval()
+	for key, counter := range s.counters {
+		if counter.suppressed > 0 {
+			if wait := interval - now.Sub(counter.suppressedSince); all || wait <= 0 {
+				summaries = append(summaries, counter.summarize(key, now))
+			} else if next == 0 || wait < next {
+				next = wait
+			}
+		}
+		idle := now.Sub(counter.tickStart) >= settings.tick && now.Sub(counter.windowStart) >= settings.window
+		if counter.suppressed == 0 && (all || idle) {
+			delete(s.counters, key)
+		}
+	}
+	return summaries, next
+}
+
+// flush writes the due summaries of every shard, or all pending ones, and
+// returns the time until the earliest remaining one is due.
+func (t *throttle) flush(all bool) time.Duration {
+	settings := t.settings.Load()
+	now := t.now()
+	var next time.Duration
+	for i := range t.shards {
+		shard := &t.shards[i]
+		shard.mu.Lock()
+		summaries, wait := shard.flush(settings, now, all)
+		shard.mu.Unlock()
+		t.write(summaries)
+		if wait > 0 && (next == 0 || wait < next) {
+			next = wait
+		}
+	}
+	return next
+}
+
+// schedule arms the flush timer to fire after d unless it is already armed.
+func (t *throttle) schedule(d time.Duration) {
+	t.timerMu.Lock()
+	defer t.timerMu.Unlock()
+	if t.timer != nil || t.stopped {
+		return
+	}
+	t.timer = time.AfterFunc(d, t.flushDue)
+}
+
+// flushDue runs on the timer and re-arms it while summaries are pending.
+// The timer is cleared first, so a drop racing with the flush arms a new one.
+func (t *throttle) flushDue() {
+	t.timerMu.Lock()
+	t.timer = nil
+	t.timerMu.Unlock()
+	if next := t.flush(false); next > 0 {
+		t.schedule(next)
+	}
+}
+
+// stop disarms the flush timer for good; Close calls it after the last Sync.
+func (t *throttle) stop() {
+	t.timerMu.Lock()
+	defer t.timerMu.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+func (t *throttle) write(summaries []throttleSummary) {
+	for _, summary := range summaries {
+		entry := zapcore.Entry{
+			Level:   summary.key.level,
+			Time:    summary.at,
+			Message: fmt.Sprintf("suppressed %d similar messages", summary.suppressed),
+		}
+		fields := []zapcore.Field{
+			zap.String("suppressed_msg", summary.key.message),
+			zap.Duration("since", summary.at.Sub(summary.since)),
+		}
+		if checked := t.out.Check(entry, nil); checked != nil {
+			checked.Write(fields...)
+		}
+	}
+}
+
+// throttleCore applies a throttle in front of the output cores.
+type throttleCore struct {
+	zapcore.Core
+	throttle *throttle
+}
+
+func (c *throttleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &throttleCore{Core: c.Core.With(fields), throttle: c.throttle}
+}
+
+func (c *throttleCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.throttle.allow(entry) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// Sync writes pending summaries before syncing the outputs.
+func (c *throttleCore) Sync() error {
+	c.throttle.flush(true)
+	return c.Core.Sync()
+}
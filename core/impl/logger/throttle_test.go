@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time      { return c.now }
+func (c *fakeClock) Add(d time.Duration) { c.now = c.now.Add(d) }
+
+func newThrottledLogger(settings throttleSettings) (*zap.Logger, *throttle, *observer.ObservedLogs, *fakeClock) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	t := newThrottle(observed, settings)
+	t.now = clock.Now
+	return zap.New(&throttleCore{Core: observed, throttle: t}), t, logs, clock
+}
+
+func countMessage(logs *observer.ObservedLogs, message string) int {
+	return logs.FilterMessage(message).Len()
+}
+
+func TestSamplingKeepsFirstThenEveryNth(t *testing.T) {
+	logger, _, logs, clock := newThrottledLogger(newThrottleSettings(
+		core.LoggerSamplingConfig{Initial: 2, Thereafter: 3},
+		core.LoggerRateLimitConfig{},
+	))
+	for i := 0; i < 8; i++ {
+		logger.Info("request completed")
+	}
+	logger.Info("other message")
+	// 1, 2 initial; then the 3rd and 6th after them: entries 5 and 8.
+	if got := countMessage(logs, "request completed"); got != 4 {
+		t.Fatalf("sampled entries = %d, want 4", got)
+	}
+	if got := countMessage(logs, "other message"); got != 1 {
+		t.Fatalf("other message entries = %d, want 1", got)
+	}
+
+	clock.Add(time.Second)
+	logger.Info("request completed")
+	summaries := logs.FilterMessage("suppressed 4 similar messages").All()
+	if len(summaries) != 1 || summaries[0].ContextMap()["suppressed_msg"] != "request completed" {
+		t.Fatalf("summaries = %v, want one for request completed", summaries)
+	}
+	if got := countMessage(logs, "request completed"); got != 5 {
+		t.Fatalf("entries after new tick = %d, want 5", got)
+	}
+}
+
+func TestRateLimitSuppressesAndSummarizesOnSync(t *testing.T) {
+	observedLogger, _, logs, clock := newThrottledLogger(newThrottleSettings(
+		core.LoggerSamplingConfig{},
+		core.LoggerRateLimitConfig{Limit: 2, Window: core.Duration(time.Minute)},
+	))
+	for i := 0; i < 10; i++ {
+		observedLogger.Warn("consul resolver refresh failed", zap.Int("attempt", i))
+		clock.Add(time.Second)
+	}
+	observedLogger.Error("consul resolver refresh failed")
+	if got := countMessage(logs, "consul resolver refresh failed"); got != 3 {
+		t.Fatalf("entries = %d, want 2 warn and 1 error", got)
+	}
+
+	if err := observedLogger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	summaries := logs.FilterMessage("suppressed 8 similar messages").All()
+	if len(summaries) != 1 || summaries[0].Level != zapcore.WarnLevel {
+		t.Fatalf("summaries = %v, want one warn summary", summaries)
+	}
+}
+
+func TestThrottleDisabledByDefaultAndNeverDropsPanics(t *testing.T) {
+	logger, throttle, logs, _ := newThrottledLogger(newThrottleSettings(core.LoggerSamplingConfig{}, core.LoggerRateLimitConfig{}))
+	for i := 0; i < 5; i++ {
+		logger.Info("same")
+	}
+	if got := countMessage(logs, "same"); got != 5 {
+		t.Fatalf("entries without config = %d, want 5", got)
+	}
+
+	throttle.configure(newThrottleSettings(core.LoggerSamplingConfig{Initial: 1}, core.LoggerRateLimitConfig{}))
+	for i := 0; i < 3; i++ {
+		logger.DPanic("broken invariant")
+	}
+	if got := countMessage(logs, "broken invariant"); got != 3 {
+		t.Fatalf("dpanic entries = %d, want 3", got)
+	}
+}
+
+func TestThrottleWritesSummaryWithoutFurtherEntries(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	throttle := newThrottle(observed, newThrottleSettings(
+		core.LoggerSamplingConfig{},
+		core.LoggerRateLimitConfig{Limit: 1, Window: core.Duration(20 * time.Millisecond)},
+	))
+	t.Cleanup(throttle.stop)
+	logger := zap.New(&throttleCore{Core: observed, throttle: throttle})
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("etcd keepalive failed")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for countMessage(logs, "suppressed 2 similar messages") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no summary written by the timer, entries = %v", logs.All())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestThrottleCountsConcurrentEntries(t *testing.T) {
+	logger, _, logs, _ := newThrottledLogger(newThrottleSettings(
+		core.LoggerSamplingConfig{},
+		core.LoggerRateLimitConfig{Limit: 10, Window: core.Duration(time.Hour)},
+	))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Info("request completed")
+				logger.Info(fmt.Sprintf("worker %d", g))
+			}
+		}()
+	}
+	wg.Wait()
+	_ = logger.Sync()
+
+	if got := countMessage(logs, "request completed"); got != 10 {
+		t.Fatalf("shared message entries = %d, want 10", got)
+	}
+	if got := countMessage(logs, "suppressed 790 similar messages"); got != 1 {
+		t.Fatalf("shared message summaries = %d, want 1", got)
+	}
+	if got := countMessage(logs, "suppressed 90 similar messages"); got != 8 {
+		t.Fatalf("per-worker summaries = %d, want 8", got)
+	}
+}
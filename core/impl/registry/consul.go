@@ -6,7 +6,6 @@ import (
 
 	consul "github.com/hashicorp/consul/api"
 	"github.com/iconnor-code/cogo/client"
-	"go.uber.org/zap"
 )
 
 func WithConsulClient(c *client.Consul) Option {
@@ -29,14 +28,15 @@ func (r *Registry) consulRegister(ctx context.Context) error {
 		ID:      instanceID,
 		Name:    name,
 		Address: address, Port: port,
+		Meta: r.config.GetRegistry().Metadata,
 		Check: &consul.AgentServiceCheck{
 			GRPC:     fmt.Sprintf("%s:%d/%s", address, port, name),
-			Interval: r.config.GetRegistry().HealthCheck.Interval,
-			Timeout:  r.config.GetRegistry().HealthCheck.Timeout,
+			Interval: r.config.GetRegistry().HealthCheck.Interval.String(),
+			Timeout:  r.config.GetRegistry().HealthCheck.Timeout.String(),
 			Status:   consul.HealthPassing,
 		},
 	}
-	r.logger.Info("consul register", zap.String("id", serviceRegistration.ID), zap.String("name", serviceRegistration.Name), zap.String("address", serviceRegistration.Address), zap.Int("port", serviceRegistration.Port))
+	r.logger.Info("consul register", "id", serviceRegistration.ID, "name", serviceRegistration.Name, "address", serviceRegistration.Address, "port", serviceRegistration.Port)
 	opts := consul.ServiceRegisterOpts{}.WithContext(ctx)
 	return r.consulClient.DefaultClient().Agent().ServiceRegisterOpts(serviceRegistration, opts)
 }
@@ -50,6 +50,6 @@ func (r *Registry) consulDeRegister(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	r.logger.Info("consul deregister", zap.String("id", instanceID))
+	r.logger.Info("consul deregister", "id", instanceID)
 	return nil
 }
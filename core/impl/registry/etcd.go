@@ -2,14 +2,15 @@ package registry
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/client"
-	"go.uber.org/zap"
+	"github.com/iconnor-code/cogo/core"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -18,7 +19,25 @@ func (r *Registry) etcdRegistryKey() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("/services/%s/%s", r.config.GetRegistry().Name, instanceID), nil
+	return core.EtcdServicePrefix(r.config.GetRegistry().Name) + instanceID, nil
+}
+
+// etcdEndpoint returns the JSON record stored under the instance key, which
+// the etcd resolver of rpcclient reads back.
+func (r *Registry) etcdEndpoint() (string, error) {
+	_, address, port, err := r.serviceConfig()
+	if err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(core.ServiceEndpoint{
+		Address:  address,
+		Port:     port,
+		Metadata: r.config.GetRegistry().Metadata,
+	})
+	if err != nil {
+		return "", cerrs.Wrap(err)
+	}
+	return string(value), nil
 }
 
 func WithEtcdClient(etcd *client.EtcdClient) Option {
@@ -36,66 +55,112 @@ func WithEtcdRegisterLeaseTTL(ttl int64) Option {
 }
 
 func (r *Registry) etcdRegister(ctx context.Context) error {
-	lease, err := r.etcdClient.Grant(ctx, r.leaseTTL)
+	key, err := r.etcdRegistryKey()
 	if err != nil {
-		return cerrs.Wrap(err)
+		return err
 	}
-	r.leaseID = lease.ID
-	instanceID, err := r.getInstanceID()
+	value, err := r.etcdEndpoint()
 	if err != nil {
 		return err
 	}
-	key, err := r.etcdRegistryKey()
+	leaseID, err := r.etcdPut(ctx, key, value)
 	if err != nil {
 		return err
 	}
+	r.setLeaseID(leaseID)
+	// The registration lives until DeRegister, not until ctx, which often
+	// only covers server startup.
+	r.keepAlive(context.WithoutCancel(ctx), key, value)
 
-	_, err = r.etcdClient.Put(ctx, key, instanceID, clientv3.WithLease(lease.ID))
+	r.logger.Info("etcd register", "key", key, "value", value, "lease_id", int64(leaseID), "lease_ttl", r.leaseTTL)
+
+	return nil
+}
+
+// etcdPut writes the instance key under a new lease.
+func (r *Registry) etcdPut(ctx context.Context, key, value string) (clientv3.LeaseID, error) {
+	lease, err := r.etcdClient.Grant(ctx, r.leaseTTL)
 	if err != nil {
-		return cerrs.Wrap(err)
+		return 0, cerrs.Wrap(err)
 	}
-	r.keepAlive(ctx)
+	if _, err := r.etcdClient.Put(ctx, key, value, clientv3.WithLease(lease.ID)); err != nil {
+		_, _ = r.etcdClient.Revoke(ctx, lease.ID)
+		return 0, cerrs.Wrap(err)
+	}
+	return lease.ID, nil
+}
 
-	r.logger.Info("etcd register", zap.String("key", key), zap.String("value", instanceID), zap.Int64("lease_id", int64(r.leaseID)), zap.Int64("lease_ttl", r.leaseTTL))
+func (r *Registry) setLeaseID(id clientv3.LeaseID) {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	r.leaseID = id
+}
 
-	return nil
+func (r *Registry) getLeaseID() clientv3.LeaseID {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	return r.leaseID
 }
 
-func (r *Registry) keepAlive(ctx context.Context) {
+// keepAlive refreshes the lease three times per TTL so a failed refresh can
+// be retried before the lease expires. When the lease is lost anyway, e.g.
+// after a network partition longer than the TTL, the instance registers
+// again under a new lease.
+func (r *Registry) keepAlive(ctx context.Context, key, value string) {
 	ctx, cancel := context.WithCancel(ctx)
-	r.leaseCancel = cancel
+	done := make(chan struct{})
+	r.leaseCancel, r.leaseDone = cancel, done
 
 	go func() {
-		interval := r.leaseTTL - 1
-		if interval <= 0 {
-			interval = 1
-		}
-		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer close(done)
+		interval := time.Duration(r.leaseTTL) * time.Second / 3
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				_, keepAliveErr := r.etcdClient.KeepAliveOnce(ctx, r.leaseID)
-				if keepAliveErr != nil {
-					r.logger.Error("etcd keepalive failed", zap.Error(keepAliveErr), zap.Int64("lease_id", int64(r.leaseID)))
-					return
-				}
 			}
+			leaseID := r.getLeaseID()
+			_, err := r.etcdClient.KeepAliveOnce(ctx, leaseID)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+			if !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+				r.logger.Warn("etcd keepalive failed, retrying", "error", err, "lease_id", int64(leaseID))
+				continue
+			}
+			r.logger.Warn("etcd lease lost, registering again", "key", key, "lease_id", int64(leaseID))
+			newLeaseID, err := r.etcdPut(ctx, key, value)
+			if err != nil {
+				r.logger.Error("etcd register again failed", "error", err, "key", key)
+				continue
+			}
+			r.setLeaseID(newLeaseID)
+			r.logger.Info("etcd register", "key", key, "value", value, "lease_id", int64(newLeaseID), "lease_ttl", r.leaseTTL)
 		}
 	}()
 }
 
-func (r *Registry) etcdDeRegister(ctx context.Context) error {
-	if r.leaseCancel != nil {
-		r.leaseCancel()
+// stopKeepAlive stops the keepalive goroutine and waits for it, so it cannot
+// register the instance again afterwards.
+func (r *Registry) stopKeepAlive() {
+	if r.leaseCancel == nil {
+		return
 	}
+	r.leaseCancel()
+	<-r.leaseDone
+	r.leaseCancel, r.leaseDone = nil, nil
+}
+
+func (r *Registry) etcdDeRegister(ctx context.Context) error {
+	r.stopKeepAlive()
 	key, err := r.etcdRegistryKey()
 	if err != nil {
 		return err
 	}
 	_, deleteErr := r.etcdClient.Delete(ctx, key)
-	_, revokeErr := r.etcdClient.Revoke(ctx, r.leaseID)
+	_, revokeErr := r.etcdClient.Revoke(ctx, r.getLeaseID())
 	return errors.Join(deleteErr, revokeErr)
 }
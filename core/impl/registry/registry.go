@@ -3,8 +3,10 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/iconnor-code/cogo/cerrs"
@@ -19,10 +21,14 @@ type Registry struct {
 	consulClient *client.Consul
 	logger       core.ILogger
 
-	etcdClient  *client.EtcdClient
-	leaseTTL    int64
-	leaseCancel context.CancelFunc
-	leaseID     clientv3.LeaseID
+	etcdClient     *client.EtcdClient
+	ownsEtcdClient bool
+	leaseTTL       int64
+	leaseCancel    context.CancelFunc
+	leaseDone      chan struct{}
+	// leaseMu guards leaseID, which keepAlive replaces after re-registering.
+	leaseMu sync.Mutex
+	leaseID clientv3.LeaseID
 }
 
 type Option func(*Registry) error
@@ -35,7 +41,7 @@ func NewRegistry(conf core.IConfig, logger core.ILogger, opts ...Option) (*Regis
 		return nil, cerrs.New("registry logger is required")
 	}
 	registry := &Registry{
-		logger: logger,
+		logger: core.ModuleLogger(logger, "registry"),
 		config: conf,
 	}
 	for _, opt := range opts {
@@ -55,9 +61,14 @@ func NewRegistry(conf core.IConfig, logger core.ILogger, opts ...Option) (*Regis
 	return registry, nil
 }
 
+// defaultEtcdLeaseTTL is the etcd lease of NewDefault without
+// registry.lease_ttl.
+const defaultEtcdLeaseTTL = 10 * time.Second
+
 // NewDefault builds the registry selected by the framework's current default
 // policy. Keeping this policy in the registry package lets transport servers
 // accept any IRegistry implementation without knowing how it is constructed.
+// An etcd registry owns its client and implements io.Closer to release it.
 func NewDefault(conf core.IConfig, logger core.ILogger) (core.IRegistry, error) {
 	if conf == nil {
 		return nil, cerrs.New("registry config is required")
@@ -67,43 +78,73 @@ func NewDefault(conf core.IConfig, logger core.ILogger) (core.IRegistry, error)
 	}
 	registryConf := conf.GetRegistry()
 	provider := strings.ToLower(strings.TrimSpace(registryConf.Provider))
-	if provider == "" || provider == "none" {
+	switch provider {
+	case "", "none":
 		return nil, nil
+	case "consul":
+		if strings.TrimSpace(conf.GetConsul().Address) == "" {
+			return nil, cerrs.New("consul address is required when registry provider is consul")
+		}
+		if err := validateInstance(registryConf); err != nil {
+			return nil, err
+		}
+		if err := validatePositiveDuration("registry health check interval", registryConf.HealthCheck.Interval); err != nil {
+			return nil, err
+		}
+		if err := validatePositiveDuration("registry health check timeout", registryConf.HealthCheck.Timeout); err != nil {
+			return nil, err
+		}
+		consul, err := client.NewConsul(conf)
+		if err != nil {
+			return nil, err
+		}
+		return NewRegistry(conf, logger, WithConsulClient(consul))
+	case "etcd":
+		if len(conf.GetEtcd().Endpoints) == 0 {
+			return nil, cerrs.New("etcd endpoints are required when registry provider is etcd")
+		}
+		if err := validateInstance(registryConf); err != nil {
+			return nil, err
+		}
+		leaseTTL := defaultEtcdLeaseTTL
+		if value := registryConf.LeaseTTL; value != 0 {
+			if value < core.Duration(time.Second) {
+				return nil, fmt.Errorf("registry lease ttl must be at least 1s, got %s", value)
+			}
+			leaseTTL = value.Duration()
+		}
+		etcd, err := client.NewEtcdClient(conf)
+		if err != nil {
+			return nil, err
+		}
+		registry, err := NewRegistry(conf, logger, WithEtcdClient(etcd), WithEtcdRegisterLeaseTTL(int64(leaseTTL/time.Second)))
+		if err != nil {
+			_ = etcd.Close()
+			return nil, err
+		}
+		registry.ownsEtcdClient = true
+		return registry, nil
 	}
-	if provider != "consul" {
-		return nil, fmt.Errorf("unsupported registry provider %q", registryConf.Provider)
-	}
-	if strings.TrimSpace(conf.GetConsul().Address) == "" {
-		return nil, cerrs.New("consul address is required when registry provider is consul")
-	}
+	return nil, fmt.Errorf("unsupported registry provider %q", registryConf.Provider)
+}
+
+// validateInstance checks the instance published by the consul and etcd
+// registries.
+func validateInstance(registryConf core.RegistryConfig) error {
 	if strings.TrimSpace(registryConf.Name) == "" {
-		return nil, cerrs.New("registry name is required when consul is configured")
+		return fmt.Errorf("registry name is required when registry provider is %s", registryConf.Provider)
 	}
 	if strings.TrimSpace(registryConf.Address) == "" {
-		return nil, cerrs.New("registry address is required when consul is configured")
+		return fmt.Errorf("registry address is required when registry provider is %s", registryConf.Provider)
 	}
 	if registryConf.Port <= 0 || registryConf.Port > 65535 {
-		return nil, cerrs.New("registry port must be between 1 and 65535 when consul is configured")
-	}
-	if err := validatePositiveDuration("registry health check interval", registryConf.HealthCheck.Interval); err != nil {
-		return nil, err
-	}
-	if err := validatePositiveDuration("registry health check timeout", registryConf.HealthCheck.Timeout); err != nil {
-		return nil, err
+		return fmt.Errorf("registry port must be between 1 and 65535 when registry provider is %s", registryConf.Provider)
 	}
-	consul, err := client.NewConsul(conf)
-	if err != nil {
-		return nil, err
-	}
-	return NewRegistry(conf, logger, WithConsulClient(consul))
+	return nil
 }
 
-func validatePositiveDuration(name, value string) error {
-	duration, err := time.ParseDuration(value)
-	if err != nil {
-		return fmt.Errorf("%s is invalid: %w", name, err)
-	}
-	if duration <= 0 {
+func validatePositiveDuration(name string, value core.Duration) error {
+	if value <= 0 {
 		return fmt.Errorf("%s must be positive", name)
 	}
 	return nil
@@ -129,6 +170,22 @@ func (r *Registry) DeRegister(ctx context.Context) error {
 	return cerrs.New("no registry client configured, please use WithConsulClient or WithEtcdClient to configure a registry client")
 }
 
+// Close releases the etcd client created by NewDefault. Clients passed with
+// WithEtcdClient or WithConsulClient stay open. It also stops the etcd lease
+// keepalive; call DeRegister first to remove the instance. It is safe to call more
+// than once.
+func (r *Registry) Close() error {
+	r.stopKeepAlive()
+	if !r.ownsEtcdClient {
+		return nil
+	}
+	r.ownsEtcdClient = false
+	if err := r.etcdClient.Close(); err != nil && !errors.Is(err, context.Canceled) {
+		return cerrs.Wrap(err)
+	}
+	return nil
+}
+
 func (r *Registry) getInstanceID() (string, error) {
 	if r.instanceID != "" {
 		return r.instanceID, nil
@@ -1,24 +1,32 @@
 package registry
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/iconnor-code/cogo/client"
 	"github.com/iconnor-code/cogo/core"
 	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type testLogger struct{}
 
-func (*testLogger) Log(...any) error       { return nil }
-func (*testLogger) Debug(string, ...any)   {}
-func (*testLogger) Info(string, ...any)    {}
-func (*testLogger) Warn(string, ...any)    {}
-func (*testLogger) Error(string, ...any)   {}
-func (*testLogger) Fatal(string, ...any)   {}
-func (*testLogger) Panic(string, ...any)   {}
-func (*testLogger) AddGlobalFields(...any) {}
+func (*testLogger) Log(...any) error           { return nil }
+func (*testLogger) Debug(string, ...any)       {}
+func (*testLogger) Info(string, ...any)        {}
+func (*testLogger) Warn(string, ...any)        {}
+func (*testLogger) Error(string, ...any)       {}
+func (*testLogger) Fatal(string, ...any)       {}
+func (*testLogger) Panic(string, ...any)       {}
+func (*testLogger) AddGlobalFields(...any)     {}
+func (l *testLogger) With(...any) core.ILogger { return l }
 
 func TestNewDefaultDisablesRegistryWithoutConsul(t *testing.T) {
 	got, err := NewDefault(&cogoconfig.Config{}, &testLogger{})
@@ -73,8 +81,8 @@ func TestNewDefaultRejectsIncompleteRegistryConfig(t *testing.T) {
 		{name: "missing name", registry: core.RegistryConfig{Provider: "consul", Address: "127.0.0.1", Port: 10000}, wantErrPart: "name"},
 		{name: "missing address", registry: core.RegistryConfig{Provider: "consul", Name: "account", Port: 10000}, wantErrPart: "address"},
 		{name: "invalid port", registry: core.RegistryConfig{Provider: "consul", Name: "account", Address: "127.0.0.1", Port: 70000}, wantErrPart: "port"},
-		{name: "invalid interval", registry: core.RegistryConfig{Provider: "consul", Name: "account", Address: "127.0.0.1", Port: 10000, HealthCheck: core.RegistryHealthCheckConfig{Interval: "bad", Timeout: "5s"}}, wantErrPart: "interval"},
-		{name: "invalid timeout", registry: core.RegistryConfig{Provider: "consul", Name: "account", Address: "127.0.0.1", Port: 10000, HealthCheck: core.RegistryHealthCheckConfig{Interval: "3s", Timeout: "0s"}}, wantErrPart: "timeout"},
+		{name: "invalid interval", registry: core.RegistryConfig{Provider: "consul", Name: "account", Address: "127.0.0.1", Port: 10000, HealthCheck: core.RegistryHealthCheckConfig{Timeout: core.Duration(5 * time.Second)}}, wantErrPart: "interval"},
+		{name: "invalid timeout", registry: core.RegistryConfig{Provider: "consul", Name: "account", Address: "127.0.0.1", Port: 10000, HealthCheck: core.RegistryHealthCheckConfig{Interval: core.Duration(3 * time.Second)}}, wantErrPart: "timeout"},
 	}
 
 	for _, tt := range tests {
@@ -91,6 +99,69 @@ func TestNewDefaultRejectsIncompleteRegistryConfig(t *testing.T) {
 	}
 }
 
+func TestNewDefaultRejectsIncompleteEtcdRegistryConfig(t *testing.T) {
+	instance := core.RegistryConfig{Provider: "etcd", Name: "account", Address: "127.0.0.1", Port: 10000}
+	tests := []struct {
+		name        string
+		endpoints   []string
+		leaseTTL    core.Duration
+		wantErrPart string
+	}{
+		{name: "missing endpoints", wantErrPart: "etcd endpoints"},
+		{name: "lease ttl below one second", endpoints: []string{"127.0.0.1:2379"}, leaseTTL: core.Duration(500 * time.Millisecond), wantErrPart: "lease ttl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registryConf := instance
+			registryConf.LeaseTTL = tt.leaseTTL
+			config := &cogoconfig.Config{Config: core.Config{
+				Etcd:     core.EtcdConfig{Endpoints: tt.endpoints},
+				Registry: registryConf,
+			}}
+			_, err := NewDefault(config, &testLogger{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+				t.Fatalf("expected %q error, got %v", tt.wantErrPart, err)
+			}
+		})
+	}
+}
+
+func TestNewDefaultBuildsEtcdRegistry(t *testing.T) {
+	config := &cogoconfig.Config{Config: core.Config{
+		Etcd: core.EtcdConfig{Endpoints: []string{"127.0.0.1:2379"}},
+		Registry: core.RegistryConfig{
+			Provider: "etcd",
+			Name:     "account",
+			Address:  "127.0.0.1",
+			Port:     10000,
+			LeaseTTL: core.Duration(15 * time.Second),
+		},
+	}}
+
+	got, err := NewDefault(config, &testLogger{})
+	if err != nil {
+		t.Fatalf("new default registry: %v", err)
+	}
+	registry, ok := got.(*Registry)
+	if !ok || registry.etcdClient == nil {
+		t.Fatalf("registry = %#v, want etcd registry", got)
+	}
+	if registry.leaseTTL != 15 {
+		t.Fatalf("lease ttl = %d, want 15", registry.leaseTTL)
+	}
+	closer, ok := got.(io.Closer)
+	if !ok {
+		t.Fatal("etcd registry does not implement io.Closer")
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+}
+
 func TestNewDefaultBuildsCompleteConsulRegistry(t *testing.T) {
 	config := &cogoconfig.Config{Config: core.Config{
 		Consul: core.ConsulConfig{Address: "127.0.0.1:8500"},
@@ -100,8 +171,8 @@ func TestNewDefaultBuildsCompleteConsulRegistry(t *testing.T) {
 			Address:  "127.0.0.1",
 			Port:     10000,
 			HealthCheck: core.RegistryHealthCheckConfig{
-				Interval: "3s",
-				Timeout:  "5s",
+				Interval: core.Duration(3 * time.Second),
+				Timeout:  core.Duration(5 * time.Second),
 			},
 		},
 	}}
@@ -114,3 +185,113 @@ func TestNewDefaultBuildsCompleteConsulRegistry(t *testing.T) {
 		t.Fatal("expected consul registry")
 	}
 }
+
+func TestEtcdRegistryWritesEndpointRecord(t *testing.T) {
+	config := &cogoconfig.Config{Config: core.Config{Registry: core.RegistryConfig{
+		Name:     "account",
+		Address:  "10.0.0.1",
+		Port:     9000,
+		Metadata: map[string]string{"zone": "a"},
+	}}}
+	r, err := NewRegistry(config, &testLogger{}, WithEtcdClient(&client.EtcdClient{}), WithEtcdRegisterLeaseTTL(5))
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+	key, err := r.etcdRegistryKey()
+	if err != nil {
+		t.Fatalf("registry key: %v", err)
+	}
+	if key != "/services/account/account-10.0.0.1:9000" {
+		t.Fatalf("key = %q", key)
+	}
+	value, err := r.etcdEndpoint()
+	if err != nil {
+		t.Fatalf("endpoint record: %v", err)
+	}
+	var endpoint core.ServiceEndpoint
+	if err := json.Unmarshal([]byte(value), &endpoint); err != nil {
+		t.Fatalf("decode endpoint record %s: %v", value, err)
+	}
+	if endpoint.Address != "10.0.0.1" || endpoint.Port != 9000 || endpoint.Metadata["zone"] != "a" {
+		t.Fatalf("endpoint record = %+v", endpoint)
+	}
+}
+
+// fakeEtcd records the registry's lease and key calls. Only the first lease
+// is lost: refreshing it fails as after an expiry.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Lease
+
+	mu      sync.Mutex
+	granted clientv3.LeaseID
+	puts    int
+	revoked []clientv3.LeaseID
+}
+
+func (f *fakeEtcd) Grant(context.Context, int64) (*clientv3.LeaseGrantResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.granted++
+	return &clientv3.LeaseGrantResponse{ID: f.granted}, nil
+}
+
+func (f *fakeEtcd) KeepAliveOnce(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	if id == 1 {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	return &clientv3.LeaseKeepAliveResponse{ID: id}, nil
+}
+
+func (f *fakeEtcd) Revoke(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked = append(f.revoked, id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (f *fakeEtcd) Put(context.Context, string, string, ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.puts++
+	return &clientv3.PutResponse{}, nil
+}
+
+func (f *fakeEtcd) Delete(context.Context, string, ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func TestEtcdKeepAliveRegistersAgainWhenLeaseIsLost(t *testing.T) {
+	etcd := &fakeEtcd{}
+	config := &cogoconfig.Config{Config: core.Config{Registry: core.RegistryConfig{Name: "account", Address: "10.0.0.1", Port: 9000}}}
+	r, err := NewRegistry(config, &testLogger{},
+		WithEtcdClient(&client.EtcdClient{Client: &clientv3.Client{KV: etcd, Lease: etcd}}),
+		WithEtcdRegisterLeaseTTL(1),
+	)
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+	if err := r.Register(context.Background()); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.getLeaseID() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("lease id = %d, want the instance registered again under lease 2", r.getLeaseID())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := r.DeRegister(context.Background()); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+	etcd.mu.Lock()
+	defer etcd.mu.Unlock()
+	if etcd.puts != 2 || etcd.granted != 2 {
+		t.Fatalf("puts = %d, grants = %d, want 2 each", etcd.puts, etcd.granted)
+	}
+	if len(etcd.revoked) != 1 || etcd.revoked[0] != 2 {
+		t.Fatalf("revoked = %v, want the new lease 2", etcd.revoked)
+	}
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/iconnor-code/cogo/core"
-	"go.uber.org/zap"
 	"google.golang.org/grpc/resolver"
 )
 
@@ -44,7 +43,7 @@ func (b *consulResolverBuilder) Build(target resolver.Target, cc resolver.Client
 	}
 	if err := r.update(); err != nil {
 		cc.ReportError(err)
-		b.logger.Warn("initial consul resolve failed", zap.String("service", service), zap.Error(err))
+		b.logger.Warn("initial consul resolve failed", "service", service, "error", err)
 	}
 	r.wg.Add(1)
 	go r.watch()
@@ -89,7 +88,7 @@ func (r *consulResolver) watch() {
 		}
 		if err := r.update(); err != nil {
 			r.cc.ReportError(err)
-			r.logger.Warn("consul resolver refresh failed", zap.String("service", r.service), zap.Error(err))
+			r.logger.Warn("consul resolver refresh failed", "service", r.service, "error", err)
 		}
 	}
 }
@@ -0,0 +1,199 @@
+package rpcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/resolver"
+)
+
+// etcdResolverBuilder resolves etcd:///<service> to the endpoint records
+// registered under core.EtcdServicePrefix(service) and watches the prefix,
+// so registrations and lease expiries apply without polling.
+type etcdResolverBuilder struct {
+	kv            clientv3.KV
+	watcher       clientv3.Watcher
+	logger        core.ILogger
+	queryTimeout  time.Duration
+	retryInterval time.Duration
+}
+
+func newEtcdResolverBuilder(kv clientv3.KV, watcher clientv3.Watcher, logger core.ILogger, queryTimeout time.Duration) *etcdResolverBuilder {
+	return &etcdResolverBuilder{kv: kv, watcher: watcher, logger: logger, queryTimeout: queryTimeout, retryInterval: defaultEtcdRetryInterval}
+}
+
+func (b *etcdResolverBuilder) Scheme() string { return "etcd" }
+
+func (b *etcdResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	service := target.Endpoint()
+	if service == "" {
+		return nil, fmt.Errorf("etcd resolver service name is required")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &etcdResolver{
+		kv:            b.kv,
+		watcher:       b.watcher,
+		logger:        b.logger,
+		queryTimeout:  b.queryTimeout,
+		retryInterval: b.retryInterval,
+		service:       service,
+		prefix:        core.EtcdServicePrefix(service),
+		cc:            cc,
+		ctx:           ctx,
+		cancel:        cancel,
+		resolveNow:    make(chan struct{}, 1),
+	}
+	if err := r.load(); err != nil {
+		cc.ReportError(err)
+		b.logger.Warn("initial etcd resolve failed", "service", service, "error", err)
+	} else {
+		r.update()
+	}
+	r.wg.Add(1)
+	go r.watch()
+	return r, nil
+}
+
+type etcdResolver struct {
+	kv            clientv3.KV
+	watcher       clientv3.Watcher
+	logger        core.ILogger
+	queryTimeout  time.Duration
+	retryInterval time.Duration
+	service       string
+	prefix        string
+	cc            resolver.ClientConn
+	ctx           context.Context
+	cancel        context.CancelFunc
+	resolveNow    chan struct{}
+	wg            sync.WaitGroup
+
+	// endpoints maps instance keys to host:port and revision is the etcd
+	// revision they reflect, 0 until the first successful load. Both are
+	// only used by Build and then the watch goroutine.
+	endpoints map[string]string
+	revision  int64
+}
+
+// ResolveNow cuts the wait before the next load short while the watch is
+// down; an established watch already delivers every change.
+func (r *etcdResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
+
+func (r *etcdResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// watch follows the prefix from the loaded revision. When the watch fails,
+// e.g. because the revision was compacted or the etcd member lost its
+// leader, the instances are loaded again and a new watch starts from the
+// new revision, so events missed in between are not lost.
+func (r *etcdResolver) watch() {
+	defer r.wg.Done()
+	for {
+		if r.revision > 0 {
+			r.follow()
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(r.retryInterval):
+		case <-r.resolveNow:
+		}
+		if err := r.load(); err != nil {
+			r.cc.ReportError(err)
+			r.logger.Warn("etcd resolver reload failed", "service", r.service, "error", err)
+			continue
+		}
+		r.update()
+	}
+}
+
+// follow applies watch events until the watch fails or the resolver closes.
+func (r *etcdResolver) follow() {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(r.ctx))
+	defer cancel()
+	for resp := range r.watcher.Watch(ctx, r.prefix, clientv3.WithPrefix(), clientv3.WithRev(r.revision+1)) {
+		if err := resp.Err(); err != nil {
+			r.logger.Warn("etcd resolver watch failed, reloading", "service", r.service, "error", err, "compact_revision", resp.CompactRevision)
+			return
+		}
+		if len(resp.Events) == 0 {
+			continue
+		}
+		for _, event := range resp.Events {
+			switch event.Type {
+			case clientv3.EventTypePut:
+				r.put(event.Kv)
+			case clientv3.EventTypeDelete:
+				delete(r.endpoints, string(event.Kv.Key))
+			}
+		}
+		r.revision = resp.Header.Revision
+		r.update()
+	}
+}
+
+// load reads all instances of the service and the revision to watch from.
+func (r *etcdResolver) load() error {
+	ctx, cancel := context.WithTimeout(r.ctx, r.queryTimeout)
+	defer cancel()
+	resp, err := r.kv.Get(ctx, r.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("resolve etcd service %s: %w", r.service, err)
+	}
+	r.endpoints = make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		r.put(kv)
+	}
+	r.revision = resp.Header.Revision
+	return nil
+}
+
+func (r *etcdResolver) put(kv *mvccpb.KeyValue) {
+	key := string(kv.Key)
+	var endpoint core.ServiceEndpoint
+	if err := json.Unmarshal(kv.Value, &endpoint); err != nil || endpoint.Address == "" || endpoint.Port <= 0 {
+		r.logger.Warn("ignoring invalid etcd endpoint record", "service", r.service, "key", key, "value", string(kv.Value))
+		delete(r.endpoints, key)
+		return
+	}
+	r.endpoints[key] = net.JoinHostPort(endpoint.Address, strconv.Itoa(endpoint.Port))
+}
+
+// update pushes the current instances to the ClientConn.
+func (r *etcdResolver) update() {
+	endpoints := make([]string, 0, len(r.endpoints))
+	for _, endpoint := range r.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	slices.Sort(endpoints)
+	endpoints = slices.Compact(endpoints)
+	if len(endpoints) == 0 {
+		err := fmt.Errorf("no etcd service instance registered: %s", r.service)
+		r.cc.ReportError(err)
+		r.logger.Warn("etcd resolver found no instances", "service", r.service)
+		return
+	}
+	addresses := make([]resolver.Address, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		addresses = append(addresses, resolver.Address{Addr: endpoint})
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		r.logger.Warn("etcd resolver update rejected", "service", r.service, "error", err)
+	}
+}
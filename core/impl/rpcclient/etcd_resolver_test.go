@@ -0,0 +1,205 @@
+package rpcclient
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/resolver"
+)
+
+// fakeEtcd serves Get from a key map and hands each Watch a channel the
+// test sends events on.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	mu       sync.Mutex
+	values   map[string]string
+	revision int64
+	watches  chan chan clientv3.WatchResponse
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{values: make(map[string]string), watches: make(chan chan clientv3.WatchResponse, 4)}
+}
+
+func (f *fakeEtcd) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revision++
+	if value == "" {
+		delete(f.values, key)
+		return
+	}
+	f.values[key] = value
+}
+
+func (f *fakeEtcd) Get(_ context.Context, prefix string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: f.revision}}
+	for key, value := range f.values {
+		if strings.HasPrefix(key, prefix) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, _ string, _ ...clientv3.OpOption) clientv3.WatchChan {
+	in := make(chan clientv3.WatchResponse)
+	out := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-in:
+				if !ok {
+					return
+				}
+				out <- resp
+			}
+		}
+	}()
+	f.watches <- in
+	return out
+}
+
+func (f *fakeEtcd) nextWatch(t *testing.T) chan clientv3.WatchResponse {
+	t.Helper()
+	select {
+	case watch := <-f.watches:
+		return watch
+	case <-time.After(5 * time.Second):
+		t.Fatal("etcd watch was not started")
+		return nil
+	}
+}
+
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	states chan []string
+}
+
+func (c *fakeClientConn) UpdateState(state resolver.State) error {
+	addrs := make([]string, 0, len(state.Addresses))
+	for _, addr := range state.Addresses {
+		addrs = append(addrs, addr.Addr)
+	}
+	sort.Strings(addrs)
+	c.states <- addrs
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(error) {}
+
+func (c *fakeClientConn) expect(t *testing.T, want ...string) {
+	t.Helper()
+	select {
+	case got := <-c.states:
+		if !slices.Equal(got, want) {
+			t.Fatalf("addresses = %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no resolver update, want %v", want)
+	}
+}
+
+func putEvent(key, value string, revision int64) clientv3.WatchResponse {
+	return clientv3.WatchResponse{
+		Header: etcdserverpb.ResponseHeader{Revision: revision},
+		Events: []*clientv3.Event{{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}},
+	}
+}
+
+func TestEtcdResolverFollowsRegistrations(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.set("/services/account/a", `{"address":"10.0.0.1","port":9000,"metadata":{"zone":"a"}}`)
+	etcd.set("/services/account/legacy", "account-10.0.0.9:9000")
+	etcd.set("/services/blog/b", `{"address":"10.0.1.1","port":9000}`)
+
+	builder := newEtcdResolverBuilder(etcd, etcd, &testLogger{}, time.Second)
+	cc := &fakeClientConn{states: make(chan []string, 4)}
+	r, err := builder.Build(resolver.Target{URL: url.URL{Scheme: "etcd", Path: "/account"}}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	defer r.Close()
+	cc.expect(t, "10.0.0.1:9000")
+
+	watch := etcd.nextWatch(t)
+	watch <- putEvent("/services/account/b", `{"address":"10.0.0.2","port":9000}`, 5)
+	cc.expect(t, "10.0.0.1:9000", "10.0.0.2:9000")
+
+	watch <- clientv3.WatchResponse{
+		Header: etcdserverpb.ResponseHeader{Revision: 6},
+		Events: []*clientv3.Event{{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("/services/account/a")}}},
+	}
+	cc.expect(t, "10.0.0.2:9000")
+
+	watch <- putEvent("/services/account/c", `{"address":"fd00::3","port":9000}`, 7)
+	cc.expect(t, "10.0.0.2:9000", "[fd00::3]:9000")
+}
+
+func TestEtcdResolverReloadsAfterCompaction(t *testing.T) {
+	etcd := newFakeEtcd()
+	etcd.set("/services/account/a", `{"address":"10.0.0.1","port":9000}`)
+
+	builder := newEtcdResolverBuilder(etcd, etcd, &testLogger{}, time.Second)
+	builder.retryInterval = time.Millisecond
+	cc := &fakeClientConn{states: make(chan []string, 4)}
+	r, err := builder.Build(resolver.Target{URL: url.URL{Scheme: "etcd", Path: "/account"}}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	defer r.Close()
+	cc.expect(t, "10.0.0.1:9000")
+
+	// Changes made while the watch revision is compacted away are picked up
+	// by the reload before watching again.
+	watch := etcd.nextWatch(t)
+	etcd.set("/services/account/a", "")
+	etcd.set("/services/account/c", `{"address":"10.0.0.3","port":9000}`)
+	watch <- clientv3.WatchResponse{CompactRevision: 2, Canceled: true}
+	close(watch)
+	cc.expect(t, "10.0.0.3:9000")
+
+	watch = etcd.nextWatch(t)
+	watch <- putEvent("/services/account/d", `{"address":"10.0.0.4","port":9000}`, 10)
+	cc.expect(t, "10.0.0.3:9000", "10.0.0.4:9000")
+}
+
+func TestPoolDialsEtcdTargets(t *testing.T) {
+	config := &cogoconfig.Config{Config: core.Config{Discovery: core.DiscoveryConfig{Provider: "etcd"}}}
+	if _, err := NewPool(config, &testLogger{}); err == nil || !strings.Contains(err.Error(), "etcd endpoints") {
+		t.Fatalf("expected missing etcd endpoints error, got %v", err)
+	}
+
+	config.Etcd.Endpoints = []string{"127.0.0.1:2379"}
+	pool, err := NewPool(config, &testLogger{})
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	conn, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("connection: %v", err)
+	}
+	if conn.Target() != "etcd:///account" {
+		t.Fatalf("target = %q, want etcd:///account", conn.Target())
+	}
+}
@@ -4,6 +4,7 @@
 package rpcclient
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,25 +12,56 @@ import (
 	"time"
 
 	"github.com/iconnor-code/cogo/client"
+	"github.com/iconnor-code/cogo/clientopt"
 	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/tlsconfig"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/resolver"
 )
 
 const defaultConsulRefreshInterval = 10 * time.Second
 const defaultConsulQueryTimeout = 3 * time.Second
 
-var roundRobinServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+// defaultEtcdQueryTimeout bounds the Get that loads the instances of a
+// service, and defaultEtcdRetryInterval is the wait before loading them
+// again after the etcd watch failed.
+const defaultEtcdQueryTimeout = 3 * time.Second
+const defaultEtcdRetryInterval = time.Second
+
+// defaultDeadlineMargin is kept back from the remaining deadline of
+// outgoing calls without discovery.deadline_margin.
+const defaultDeadlineMargin = 10 * time.Millisecond
+
+// retiredConnGrace is how long a connection replaced by a discovery config
+// reload stays open so in-flight calls can finish.
+const retiredConnGrace = 30 * time.Second
 
 // Pool lazily creates and reuses one gRPC ClientConn per logical service.
 // Close must be called by the process lifecycle owner.
+// Discovery targets are read from config on each new connection; when the
+// config supports reloads, connections whose target changed are replaced.
+// Each service gets a circuit breaker, a bulkhead and a retry budget
+// configured by discovery.policies; they outlive replaced connections.
 type Pool struct {
-	config   core.DiscoveryConfig
-	resolver *consulResolverBuilder
+	config   core.IConfig
+	provider string
+	resolver resolver.Builder
+	etcd     *client.EtcdClient
+	metrics  *clientopt.ClientMetrics
+	logger   core.ILogger
+	creds    credentials.TransportCredentials
 
-	mu     sync.Mutex
-	conns  map[string]*grpc.ClientConn
-	closed bool
+	mu             sync.Mutex
+	conns          map[string]*grpc.ClientConn
+	targets        map[string]string
+	serviceConfigs map[string]string
+	retired        map[*grpc.ClientConn]struct{}
+	breakers       map[string]*clientopt.CircuitBreaker
+	bulkheads      map[string]*clientopt.Bulkhead
+	budgets        map[string]*clientopt.RetryBudget
+	closed         bool
 }
 
 var _ core.IRPCClient = (*Pool)(nil)
@@ -43,11 +75,35 @@ func NewPool(config core.IConfig, logger core.ILogger) (*Pool, error) {
 
 	discoveryConfig := config.GetDiscovery()
 	provider := strings.ToLower(strings.TrimSpace(discoveryConfig.Provider))
-	pool := &Pool{config: discoveryConfig, conns: make(map[string]*grpc.ClientConn)}
+	pool := &Pool{
+		config:         config,
+		provider:       provider,
+		conns:          make(map[string]*grpc.ClientConn),
+		targets:        make(map[string]string),
+		serviceConfigs: make(map[string]string),
+		retired:        make(map[*grpc.ClientConn]struct{}),
+		breakers:       make(map[string]*clientopt.CircuitBreaker),
+		bulkheads:      make(map[string]*clientopt.Bulkhead),
+		budgets:        make(map[string]*clientopt.RetryBudget),
+	}
+	if logger != nil {
+		pool.logger = core.ModuleLogger(logger, "rpcclient")
+	}
+	creds, err := tlsconfig.ClientCredentials(config.GetTLS(), pool.logger)
+	if err != nil {
+		return nil, fmt.Errorf("init rpc client tls: %w", err)
+	}
+	pool.creds = creds
+	if metricsConf := config.GetMetrics(); metricsConf.Enable {
+		metrics, err := clientopt.NewClientMetrics(metricsConf.Prefix, nil)
+		if err != nil {
+			return nil, fmt.Errorf("init rpc client metrics: %w", err)
+		}
+		pool.metrics = metrics
+	}
 
 	switch provider {
 	case "", "none", "dns":
-		return pool, nil
 	case "consul":
 		if logger == nil {
 			return nil, errors.New("rpc client logger is required for consul discovery")
@@ -56,30 +112,55 @@ func NewPool(config core.IConfig, logger core.ILogger) (*Pool, error) {
 			return nil, errors.New("consul address is required for consul discovery")
 		}
 		refreshInterval := defaultConsulRefreshInterval
-		if value := strings.TrimSpace(discoveryConfig.RefreshInterval); value != "" {
-			parsed, err := time.ParseDuration(value)
-			if err != nil || parsed <= 0 {
+		if value := discoveryConfig.RefreshInterval; value != 0 {
+			if value < 0 {
 				return nil, fmt.Errorf("discovery refresh interval must be a positive duration: %q", value)
 			}
-			refreshInterval = parsed
+			refreshInterval = value.Duration()
 		}
-		queryTimeout := defaultConsulQueryTimeout
-		if value := strings.TrimSpace(discoveryConfig.Timeout); value != "" {
-			parsed, err := time.ParseDuration(value)
-			if err != nil || parsed <= 0 {
-				return nil, fmt.Errorf("discovery timeout must be a positive duration: %q", value)
-			}
-			queryTimeout = parsed
+		queryTimeout, err := discoveryTimeout(discoveryConfig, defaultConsulQueryTimeout)
+		if err != nil {
+			return nil, err
 		}
 		consul, err := client.NewConsul(config)
 		if err != nil {
 			return nil, err
 		}
-		pool.resolver = newConsulResolverBuilder(consul.DefaultClient(), logger, refreshInterval, queryTimeout)
-		return pool, nil
+		pool.resolver = newConsulResolverBuilder(consul.DefaultClient(), pool.logger, refreshInterval, queryTimeout)
+	case "etcd":
+		if logger == nil {
+			return nil, errors.New("rpc client logger is required for etcd discovery")
+		}
+		if len(config.GetEtcd().Endpoints) == 0 {
+			return nil, errors.New("etcd endpoints are required for etcd discovery")
+		}
+		queryTimeout, err := discoveryTimeout(discoveryConfig, defaultEtcdQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		etcd, err := client.NewEtcdClient(config)
+		if err != nil {
+			return nil, err
+		}
+		pool.etcd = etcd
+		pool.resolver = newEtcdResolverBuilder(etcd, etcd, pool.logger, queryTimeout)
 	default:
 		return nil, fmt.Errorf("unsupported discovery provider %q", discoveryConfig.Provider)
 	}
+	if watcher, ok := config.(core.IConfigWatcher); ok {
+		watcher.Subscribe("discovery", pool.onDiscoveryChange)
+	}
+	return pool, nil
+}
+
+func discoveryTimeout(conf core.DiscoveryConfig, fallback time.Duration) (time.Duration, error) {
+	if value := conf.Timeout; value != 0 {
+		if value < 0 {
+			return 0, fmt.Errorf("discovery timeout must be a positive duration: %q", value)
+		}
+		return value.Duration(), nil
+	}
+	return fallback, nil
 }
 
 // Conn returns a shared connection for service. DNS targets can be ordinary
@@ -99,7 +180,8 @@ func (p *Pool) Conn(service string) (*grpc.ClientConn, error) {
 		return conn, nil
 	}
 
-	target, opts, err := p.targetAndOptions(service)
+	serviceConfig := clientopt.ServiceConfig(p.config.GetDiscovery().Policy(service))
+	target, opts, err := p.targetAndOptions(service, serviceConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -108,29 +190,122 @@ func (p *Pool) Conn(service string) (*grpc.ClientConn, error) {
 		return nil, fmt.Errorf("create grpc client for %s: %w", service, err)
 	}
 	p.conns[service] = conn
+	p.targets[service] = target
+	p.serviceConfigs[service] = serviceConfig
 	return conn, nil
 }
 
-func (p *Pool) targetAndOptions(service string) (string, []grpc.DialOption, error) {
-	provider := strings.ToLower(strings.TrimSpace(p.config.Provider))
+// onDiscoveryChange retires connections whose configured target or service
+// config changed so the next Conn call dials with the new settings. Targets
+// of resolver-backed providers come from the registry, not from
+// discovery.services, so only their service config is compared.
+func (p *Pool) onDiscoveryChange(_, next core.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	for service, conn := range p.conns {
+		sameTarget := p.resolver != nil || strings.TrimSpace(next.Discovery.Services[service]) == p.targets[service]
+		if sameTarget && clientopt.ServiceConfig(next.Discovery.Policy(service)) == p.serviceConfigs[service] {
+			continue
+		}
+		delete(p.conns, service)
+		delete(p.targets, service)
+		delete(p.serviceConfigs, service)
+		p.retired[conn] = struct{}{}
+		time.AfterFunc(retiredConnGrace, func() { p.closeRetired(conn) })
+	}
+}
+
+func (p *Pool) closeRetired(conn *grpc.ClientConn) {
+	p.mu.Lock()
+	_, ok := p.retired[conn]
+	delete(p.retired, conn)
+	p.mu.Unlock()
+	if ok {
+		_ = conn.Close()
+	}
+}
+
+func (p *Pool) targetAndOptions(service, serviceConfig string) (string, []grpc.DialOption, error) {
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(roundRobinServiceConfig),
+		grpc.WithTransportCredentials(p.creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		clientopt.RequestIDOption(),
+		clientopt.RequestIDStreamOption(),
+		clientopt.DeadlineOption(p.deadlineMargin),
+		clientopt.DeadlineStreamOption(p.deadlineMargin),
 	}
-	if provider == "consul" {
-		return "consul:///" + service, append(opts, grpc.WithResolvers(p.resolver)), nil
+	if p.metrics != nil {
+		opts = append(opts,
+			clientopt.MetricsOption(p.metrics, service),
+			clientopt.MetricsStreamOption(p.metrics, service),
+		)
 	}
-	if provider == "" || provider == "none" {
+	breaker, bulkhead, budget := p.protection(service)
+	// Each retry passes the bulkhead and the breaker again; calls they
+	// reject are not retried. The bulkhead runs before the breaker so that
+	// time spent waiting for a slot does not count as a slow call.
+	opts = append(opts,
+		clientopt.RetryOption(service, p.policy(service), budget, p.metrics),
+		clientopt.BulkheadOption(bulkhead),
+		clientopt.CircuitBreakerOption(breaker),
+		clientopt.CircuitBreakerStreamOption(breaker),
+	)
+	if p.resolver != nil {
+		return p.resolver.Scheme() + ":///" + service, append(opts, grpc.WithResolvers(p.resolver)), nil
+	}
+	if p.provider == "" || p.provider == "none" {
 		return "", nil, errors.New("service discovery is disabled")
 	}
-	target := strings.TrimSpace(p.config.Services[service])
+	target := strings.TrimSpace(p.config.GetDiscovery().Services[service])
 	if target == "" {
 		return "", nil, fmt.Errorf("discovery target for service %q is required", service)
 	}
 	return target, opts, nil
 }
 
-// Close closes all connections. It is safe to call more than once.
+// protection returns the circuit breaker, bulkhead and retry budget of
+// service, creating them on first use. p.mu must be held.
+func (p *Pool) protection(service string) (*clientopt.CircuitBreaker, *clientopt.Bulkhead, *clientopt.RetryBudget) {
+	breaker := p.breakers[service]
+	if breaker == nil {
+		breaker = clientopt.NewCircuitBreaker(service, func() core.CircuitBreakerConfig {
+			return p.config.GetDiscovery().Policy(service).CircuitBreaker
+		}, p.logger, p.metrics)
+		p.breakers[service] = breaker
+	}
+	bulkhead := p.bulkheads[service]
+	if bulkhead == nil {
+		bulkhead = clientopt.NewBulkhead(service, func() core.BulkheadConfig {
+			return p.config.GetDiscovery().Policy(service).Bulkhead
+		}, p.metrics)
+		p.bulkheads[service] = bulkhead
+	}
+	budget := p.budgets[service]
+	if budget == nil {
+		budget = clientopt.NewRetryBudget()
+		p.budgets[service] = budget
+	}
+	return breaker, bulkhead, budget
+}
+
+func (p *Pool) policy(service string) func() core.ServicePolicyConfig {
+	return func() core.ServicePolicyConfig {
+		return p.config.GetDiscovery().Policy(service)
+	}
+}
+
+func (p *Pool) deadlineMargin() time.Duration {
+	if margin := p.config.GetDiscovery().DeadlineMargin; margin > 0 {
+		return margin.Duration()
+	}
+	return defaultDeadlineMargin
+}
+
+// Close closes all connections and the etcd client of etcd discovery. It is safe to call more than once.
 func (p *Pool) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -144,6 +319,18 @@ func (p *Pool) Close() error {
 			errs = errors.Join(errs, fmt.Errorf("close grpc client for %s: %w", service, err))
 		}
 	}
+	for conn := range p.retired {
+		if err := conn.Close(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("close retired grpc client for %s: %w", conn.Target(), err))
+		}
+	}
 	p.conns = nil
+	p.retired = nil
+	// Closing the connections stopped the resolvers that use the client.
+	if p.etcd != nil {
+		if err := p.etcd.Close(); err != nil && !errors.Is(err, context.Canceled) {
+			errs = errors.Join(errs, fmt.Errorf("close etcd discovery client: %w", err))
+		}
+	}
 	return errs
 }
@@ -1,8 +1,11 @@
 package rpcclient
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/iconnor-code/cogo/core"
 	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
@@ -65,7 +68,7 @@ func TestPoolRejectsInvalidConsulTimeout(t *testing.T) {
 		Consul: core.ConsulConfig{Address: "127.0.0.1:8500"},
 		Discovery: core.DiscoveryConfig{
 			Provider: "consul",
-			Timeout:  "0s",
+			Timeout:  core.Duration(-time.Second),
 		},
 	}}
 	if _, err := NewPool(config, &testLogger{}); err == nil || !strings.Contains(err.Error(), "timeout") {
@@ -75,11 +78,173 @@ func TestPoolRejectsInvalidConsulTimeout(t *testing.T) {
 
 type testLogger struct{}
 
-func (*testLogger) Log(...any) error       { return nil }
-func (*testLogger) Debug(string, ...any)   {}
-func (*testLogger) Info(string, ...any)    {}
-func (*testLogger) Warn(string, ...any)    {}
-func (*testLogger) Error(string, ...any)   {}
-func (*testLogger) Fatal(string, ...any)   {}
-func (*testLogger) Panic(string, ...any)   {}
-func (*testLogger) AddGlobalFields(...any) {}
+func (*testLogger) Log(...any) error           { return nil }
+func (*testLogger) Debug(string, ...any)       {}
+func (*testLogger) Info(string, ...any)        {}
+func (*testLogger) Warn(string, ...any)        {}
+func (*testLogger) Error(string, ...any)       {}
+func (*testLogger) Fatal(string, ...any)       {}
+func (*testLogger) Panic(string, ...any)       {}
+func (*testLogger) AddGlobalFields(...any)     {}
+func (l *testLogger) With(...any) core.ILogger { return l }
+
+func TestPoolInstallsClientMetricsWhenEnabled(t *testing.T) {
+	config := &cogoconfig.Config{Config: core.Config{
+		Metrics: core.MetricsConfig{Enable: true, Prefix: "account"},
+		Discovery: core.DiscoveryConfig{
+			Provider: "dns",
+			Services: map[string]string{"blog": "dns:///blog:10000"},
+		},
+	}}
+	pool, err := NewPool(config, nil)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	if pool.metrics == nil {
+		t.Fatal("expected client metrics to be configured")
+	}
+	if _, err := pool.Conn("blog"); err != nil {
+		t.Fatalf("connection: %v", err)
+	}
+}
+
+func TestPoolReplacesConnectionWhenDiscoveryTargetChanges(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	write := func(target string) {
+		content := "discovery:\n  provider: dns\n  services:\n    account: " + target + "\n    blog: dns:///blog:10000\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	write("dns:///account:10000")
+	config, err := cogoconfig.NewConfig(cogoconfig.WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	pool, err := NewPool(config, nil)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	account, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection: %v", err)
+	}
+	blog, err := pool.Conn("blog")
+	if err != nil {
+		t.Fatalf("blog connection: %v", err)
+	}
+
+	write("dns:///account-v2:10000")
+	if err := config.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	nextAccount, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection after reload: %v", err)
+	}
+	if nextAccount == account || nextAccount.Target() != "dns:///account-v2:10000" {
+		t.Fatalf("account target = %q, want new connection to dns:///account-v2:10000", nextAccount.Target())
+	}
+	if sameBlog, _ := pool.Conn("blog"); sameBlog != blog {
+		t.Fatal("unchanged blog connection was replaced")
+	}
+}
+
+func TestPoolReplacesConnectionWhenRetryPolicyChanges(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	write := func(maxAttempts string) {
+		content := "discovery:\n  provider: dns\n  services:\n    account: dns:///account:10000\n" +
+			"  policies:\n    account:\n      retry:\n        max_attempts: " + maxAttempts + "\n        methods: [account.AccountService/Get]\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	write("2")
+	config, err := cogoconfig.NewConfig(cogoconfig.WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	pool, err := NewPool(config, nil)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	account, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection: %v", err)
+	}
+	write("3")
+	if err := config.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	next, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection after reload: %v", err)
+	}
+	if next == account {
+		t.Fatal("connection was kept after its retry policy changed")
+	}
+	if !strings.Contains(pool.serviceConfigs["account"], `"maxAttempts":3`) {
+		t.Fatalf("service config = %s, want maxAttempts 3", pool.serviceConfigs["account"])
+	}
+}
+
+func TestPoolReplacesEtcdConnectionWhenRetryPolicyChanges(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	write := func(maxAttempts, target string) {
+		content := "etcd:\n  endpoints: [127.0.0.1:2379]\n" +
+			"discovery:\n  provider: etcd\n  services:\n    account: " + target + "\n" +
+			"  policies:\n    account:\n      retry:\n        max_attempts: " + maxAttempts + "\n        methods: [account.AccountService/Get]\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	write("2", "dns:///account:10000")
+	config, err := cogoconfig.NewConfig(cogoconfig.WithFilePath(configPath))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	pool, err := NewPool(config, &testLogger{})
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	account, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection: %v", err)
+	}
+
+	// etcd resolves targets from the registry, so discovery.services is
+	// not compared.
+	write("2", "dns:///account-v2:10000")
+	if err := config.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if same, _ := pool.Conn("account"); same != account {
+		t.Fatal("etcd connection was replaced after an unused discovery.services change")
+	}
+
+	write("3", "dns:///account-v2:10000")
+	if err := config.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	next, err := pool.Conn("account")
+	if err != nil {
+		t.Fatalf("account connection after reload: %v", err)
+	}
+	if next == account || next.Target() != "etcd:///account" {
+		t.Fatalf("target = %q; want a new etcd:///account connection after the retry policy changed", next.Target())
+	}
+	if !strings.Contains(pool.serviceConfigs["account"], `"maxAttempts":3`) {
+		t.Fatalf("service config = %s, want maxAttempts 3", pool.serviceConfigs["account"])
+	}
+}
@@ -13,10 +13,17 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/tlsconfig"
+	"github.com/iconnor-code/cogo/core/impl/tracing"
+	cogointerceptor "github.com/iconnor-code/cogo/interceptor"
 	"github.com/iconnor-code/cogo/pkg/token"
-	"go.uber.org/zap"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type GatewayRegister func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error
@@ -107,13 +114,24 @@ func NewGatewayMux(ctx context.Context, config core.IConfig, registers ...Gatewa
 	}
 	mux := runtime.NewServeMux(
 		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMiddlewares(tracingMiddleware),
 	)
 
 	endpoint, err := GRPCEndpoint(config)
 	if err != nil {
 		return nil, err
 	}
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	// The gateway dials its own gRPC server with the tls section, so a
+	// loopback endpoint needs tls.server_name to match the certificate.
+	creds, err := tlsconfig.ClientCredentials(config.GetTLS(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("init gateway tls: %w", err)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
 	for _, register := range registers {
 		if register == nil {
 			return nil, errors.New("gateway register function is required")
@@ -125,6 +143,54 @@ func NewGatewayMux(ctx context.Context, config core.IConfig, registers ...Gatewa
 	return mux, nil
 }
 
+// tracingMiddleware starts a server span for each gateway request, continuing
+// any W3C traceparent sent by the HTTP caller. The gateway's gRPC client
+// handler then propagates the span to the backend.
+func tracingMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := r.URL.Path
+		if pattern, ok := runtime.HTTPPattern(ctx); ok {
+			route = pattern.String()
+		}
+		ctx, span := otel.Tracer(tracing.InstrumentationName).Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r.WithContext(ctx), pathParams)
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(recorder.status))
+		}
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func incomingHeaderMatcher(header string) (string, bool) {
 	if strings.EqualFold(header, token.AuthorizationHeader) {
 		return token.AuthorizationHeader, true
@@ -135,9 +201,25 @@ func incomingHeaderMatcher(header string) (string, bool) {
 	if strings.EqualFold(header, "x-biz-name") {
 		return "biz_name", true
 	}
+	if strings.EqualFold(header, core.RequestIDHeader) {
+		return core.RequestIDHeader, true
+	}
 	return runtime.DefaultHeaderMatcher(header)
 }
 
+// outgoingHeaderMatcher returns the request ID and the retry delay of rate
+// limited calls as the X-Request-Id and Retry-After response headers; other
+// metadata keeps the default Grpc-Metadata- prefix.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, core.RequestIDHeader) {
+		return "X-Request-Id", true
+	}
+	if strings.EqualFold(key, cogointerceptor.RetryAfterHeader) {
+		return "Retry-After", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
 func (s *HTTPServer) Start(context.Context) (err error) {
 	if err := s.lifecycle.beginStart(); err != nil {
 		return err
@@ -214,7 +296,7 @@ func (s *HTTPServer) Start(context.Context) (err error) {
 		}
 	}
 	go func() {
-		s.logger.Info(protocol+" server start", zap.String("listen", httpConf.Listen))
+		s.logger.Info(protocol+" server start", "listen", httpConf.Listen)
 		var serveErr error
 		if protocol == "https" {
 			serveErr = s.server.ServeTLS(listener, "", "")
@@ -15,6 +15,7 @@ func TestIncomingHeaderMatcher(t *testing.T) {
 		{header: "X-Biz-ID", want: "biz_id"},
 		{header: "x-biz-name", want: "biz_name"},
 		{header: "Authorization", want: "authorization"},
+		{header: "X-Request-Id", want: "x-request-id"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.header, func(t *testing.T) {
@@ -26,6 +27,15 @@ func TestIncomingHeaderMatcher(t *testing.T) {
 	}
 }
 
+func TestOutgoingHeaderMatcherReturnsRequestID(t *testing.T) {
+	if got, ok := outgoingHeaderMatcher("x-request-id"); !ok || got != "X-Request-Id" {
+		t.Fatalf("outgoingHeaderMatcher(x-request-id) = (%q, %v)", got, ok)
+	}
+	if got, ok := outgoingHeaderMatcher("x-custom"); !ok || got != "Grpc-Metadata-x-custom" {
+		t.Fatalf("outgoingHeaderMatcher(x-custom) = (%q, %v)", got, ok)
+	}
+}
+
 func TestSwaggerHandlerWithoutSpecFallsBackSafely(t *testing.T) {
 	api := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
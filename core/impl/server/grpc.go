@@ -13,8 +13,11 @@ import (
 
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/tlsconfig"
+	"github.com/iconnor-code/cogo/core/impl/tracing"
 	cogointerceptor "github.com/iconnor-code/cogo/interceptor"
-	"go.uber.org/zap"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -56,10 +59,32 @@ type GrpcServerOption func(*GrpcServer) error
 type GrpcServiceOption struct {
 	PublicMethods          []string
 	TokenRevocationChecker cogointerceptor.TokenRevocationChecker
-	UnaryInterceptors      []grpc.UnaryServerInterceptor
-	RegisterServices       func(*grpc.Server) error
-	Registry               core.IRegistry
-	Closers                []io.Closer
+	// RequestLogOptions tune the unary request log, e.g. WithPayloadMaskOption;
+	// grpc.payload_log is always applied.
+	RequestLogOptions []cogointerceptor.RequestLogOption
+	// DefaultTimeout is the deadline of unary handler contexts whose
+	// caller set no earlier one; 0 leaves them without one. Handlers stop
+	// only where they watch ctx.
+	DefaultTimeout time.Duration
+	// MethodTimeouts overrides DefaultTimeout per full method name and
+	// also applies to streaming methods.
+	MethodTimeouts map[string]time.Duration
+	// MinDeadlineBudget logs requests arriving with less time left;
+	// 0 means 10ms.
+	MinDeadlineBudget time.Duration
+	// RateLimiter holds the buckets of grpc.rate_limit, e.g.
+	// interceptor.NewRedisRateLimiter to share limits across replicas;
+	// nil keeps them in process.
+	RateLimiter cogointerceptor.RateLimiter
+	// TracerProvider is a provider the caller already installed with
+	// tracing.NewProvider; nil builds one from the tracing section, which
+	// the server flushes and closes after everything else in Closers.
+	TracerProvider     *tracing.Provider
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	RegisterServices   func(*grpc.Server) error
+	Registry           core.IRegistry
+	Closers            []io.Closer
 }
 
 func WithGrpcRegistry(registry core.IRegistry) GrpcServerOption {
@@ -94,9 +119,42 @@ func NewGrpcServiceServer(config core.IConfig, logger core.ILogger, opt GrpcServ
 	if err := validateServerDependencies(config, logger); err != nil {
 		return nil, err
 	}
-	baseServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
-		unaryInterceptors(config, logger, opt)...,
-	))
+	if opt.TracerProvider == nil {
+		provider, err := tracing.NewProvider(context.Background(), config)
+		if err != nil {
+			_ = closeResources(opt.Closers)
+			return nil, fmt.Errorf("init tracing: %w", err)
+		}
+		// Closers run in reverse, so spans of the other resources are
+		// still exported.
+		opt.Closers = append([]io.Closer{provider}, opt.Closers...)
+	}
+	var serverMetrics *cogointerceptor.ServerMetrics
+	if metricsEnabled(config) {
+		var err error
+		serverMetrics, err = cogointerceptor.NewServerMetrics(config.GetMetrics().Prefix, nil,
+			cogointerceptor.WithKnownCallers(func() []string { return config.GetMetrics().Callers }))
+		if err != nil {
+			_ = closeResources(opt.Closers)
+			return nil, fmt.Errorf("init grpc server metrics: %w", err)
+		}
+	}
+	if opt.RateLimiter == nil {
+		opt.RateLimiter = cogointerceptor.NewLocalRateLimiter()
+	}
+	creds, err := tlsconfig.ServerCredentials(config.GetTLS(), core.ModuleLogger(logger, "tls"))
+	if err != nil {
+		_ = closeResources(opt.Closers)
+		return nil, fmt.Errorf("init grpc server tls: %w", err)
+	}
+	baseServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithFilter(filters.Not(filters.HealthCheck())),
+		)),
+		grpc.ChainUnaryInterceptor(unaryInterceptors(config, logger, serverMetrics, opt)...),
+		grpc.ChainStreamInterceptor(streamInterceptors(config, logger, serverMetrics, opt)...),
+	)
 	if err := registerUnaryServices(baseServer, opt); err != nil {
 		_ = closeResources(opt.Closers)
 		return nil, err
@@ -139,7 +197,7 @@ func (s *GrpcServer) Start(ctx context.Context) (err error) {
 	}
 
 	go func() {
-		s.logger.Info("grpc server start", zap.String("listen", listen))
+		s.logger.Info("grpc server start", "listen", listen)
 		serveErr := s.baseServer.Serve(listener)
 		if errors.Is(serveErr, grpc.ErrServerStopped) || errors.Is(serveErr, net.ErrClosed) {
 			serveErr = nil
@@ -354,10 +412,27 @@ func publicMethodsWithHealth(methods []string) []string {
 	)
 }
 
-func unaryInterceptors(config core.IConfig, logger core.ILogger, opt GrpcServiceOption) []grpc.UnaryServerInterceptor {
+func timeoutConfig(opt GrpcServiceOption) cogointerceptor.TimeoutConfig {
+	return cogointerceptor.TimeoutConfig{
+		Default:   opt.DefaultTimeout,
+		Methods:   opt.MethodTimeouts,
+		MinBudget: opt.MinDeadlineBudget,
+	}
+}
+
+func unaryInterceptors(config core.IConfig, logger core.ILogger, metrics *cogointerceptor.ServerMetrics, opt GrpcServiceOption) []grpc.UnaryServerInterceptor {
 	interceptors := []grpc.UnaryServerInterceptor{
+		cogointerceptor.RequestIDInterceptor(),
 		cogointerceptor.SrvCtxInterceptor(logger),
-		cogointerceptor.RequestLogInterceptor(),
+	}
+	if metrics != nil {
+		interceptors = append(interceptors, cogointerceptor.MetricsInterceptor(metrics))
+	}
+	interceptors = append(interceptors,
+		cogointerceptor.RequestLogInterceptorWithOptions(
+			append([]cogointerceptor.RequestLogOption{cogointerceptor.WithPayloadLog(config)}, opt.RequestLogOptions...)...,
+		),
+		cogointerceptor.TimeoutInterceptor(timeoutConfig(opt)),
 		cogointerceptor.ErrorInterceptor(),
 		cogointerceptor.RecoveryInterceptor(),
 		cogointerceptor.CycleCheckInterceptor(),
@@ -367,10 +442,36 @@ func unaryInterceptors(config core.IConfig, logger core.ILogger, opt GrpcService
 			publicMethodsWithHealth(opt.PublicMethods),
 			cogointerceptor.WithTokenRevocationChecker(opt.TokenRevocationChecker),
 		),
-	}
+		cogointerceptor.RateLimitInterceptor(config, opt.RateLimiter, metrics),
+	)
 	return append(interceptors, opt.UnaryInterceptors...)
 }
 
+func streamInterceptors(config core.IConfig, logger core.ILogger, metrics *cogointerceptor.ServerMetrics, opt GrpcServiceOption) []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		cogointerceptor.RequestIDStreamInterceptor(),
+		cogointerceptor.SrvCtxStreamInterceptor(logger),
+	}
+	if metrics != nil {
+		interceptors = append(interceptors, cogointerceptor.MetricsStreamInterceptor(metrics))
+	}
+	interceptors = append(interceptors,
+		cogointerceptor.RequestLogStreamInterceptor(),
+		cogointerceptor.TimeoutStreamInterceptor(timeoutConfig(opt)),
+		cogointerceptor.ErrorStreamInterceptor(),
+		cogointerceptor.RecoveryStreamInterceptor(),
+		cogointerceptor.CycleCheckStreamInterceptor(),
+		cogointerceptor.BizInfoStreamInterceptor(config),
+		cogointerceptor.UserInfoStreamInterceptorWithOptions(
+			config,
+			publicMethodsWithHealth(opt.PublicMethods),
+			cogointerceptor.WithTokenRevocationChecker(opt.TokenRevocationChecker),
+		),
+		cogointerceptor.RateLimitStreamInterceptor(config, opt.RateLimiter, metrics),
+	)
+	return append(interceptors, opt.StreamInterceptors...)
+}
+
 func registerUnaryServices(baseServer *grpc.Server, opt GrpcServiceOption) error {
 	if opt.RegisterServices == nil {
 		return nil
@@ -10,9 +10,18 @@ import (
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
 )
 
+// logLevelPath serves the logger levels next to the metrics, so they stay
+// on the internal listener rather than the public gateway.
+const logLevelPath = "/log/level"
+
+// levelHandlerProvider is implemented by loggers whose levels can be changed
+// at runtime, such as logger.Logger.
+type levelHandlerProvider interface {
+	LevelHandler() http.Handler
+}
+
 type MetricsServer struct {
 	config    core.IConfig
 	logger    core.ILogger
@@ -47,9 +56,14 @@ func (s *MetricsServer) Start(context.Context) (err error) {
 	if strings.TrimSpace(listen) == "" {
 		return errors.New("metrics listen address is required")
 	}
+	mux := http.NewServeMux()
+	mux.Handle("/", promhttp.Handler())
+	if leveled, ok := s.logger.(levelHandlerProvider); ok {
+		mux.Handle(logLevelPath, leveled.LevelHandler())
+	}
 	httpSrv := &http.Server{
 		Addr:              listen,
-		Handler:           promhttp.Handler(),
+		Handler:           mux,
 		ReadHeaderTimeout: defaultReadHeaderTimeout,
 		IdleTimeout:       defaultIdleTimeout,
 	}
@@ -58,7 +72,7 @@ func (s *MetricsServer) Start(context.Context) (err error) {
 		return cerrs.Wrap(err)
 	}
 	go func() {
-		s.logger.Info("metrics server start", zap.String("listen", listen))
+		s.logger.Info("metrics server start", "listen", listen)
 		serveErr := httpSrv.Serve(listener)
 		if errors.Is(serveErr, http.ErrServerClosed) || errors.Is(serveErr, net.ErrClosed) {
 			serveErr = nil
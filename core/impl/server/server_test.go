@@ -4,27 +4,32 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/iconnor-code/cogo/core"
 	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/test/bufconn"
 )
 
 type testLogger struct{}
 
-func (l *testLogger) Log(...any) error       { return nil }
-func (l *testLogger) Debug(string, ...any)   {}
-func (l *testLogger) Info(string, ...any)    {}
-func (l *testLogger) Warn(string, ...any)    {}
-func (l *testLogger) Error(string, ...any)   {}
-func (l *testLogger) Fatal(string, ...any)   {}
-func (l *testLogger) Panic(string, ...any)   {}
-func (l *testLogger) AddGlobalFields(...any) {}
+func (l *testLogger) Log(...any) error         { return nil }
+func (l *testLogger) Debug(string, ...any)     {}
+func (l *testLogger) Info(string, ...any)      {}
+func (l *testLogger) Warn(string, ...any)      {}
+func (l *testLogger) Error(string, ...any)     {}
+func (l *testLogger) Fatal(string, ...any)     {}
+func (l *testLogger) Panic(string, ...any)     {}
+func (l *testLogger) AddGlobalFields(...any)   {}
+func (l *testLogger) With(...any) core.ILogger { return l }
 
 type testRegistry struct {
 	registered    bool
@@ -436,3 +441,52 @@ func TestServerLifecycleRejectsInvalidCallOrder(t *testing.T) {
 }
 
 var _ core.IRegistry = (*testRegistry)(nil)
+
+func TestNewGrpcServiceServerAppliesStreamInterceptors(t *testing.T) {
+	var streamed atomic.Bool
+	config := &cogoconfig.Config{Config: core.Config{GRPC: core.GRPCConfig{Listen: "bufconn"}}}
+	s, err := NewGrpcServiceServer(config, &testLogger{}, GrpcServiceOption{
+		StreamInterceptors: []grpc.StreamServerInterceptor{
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if _, ok := core.SrvCtxFromContext(ss.Context()); !ok {
+					return errors.New("srvctx is missing from stream context")
+				}
+				streamed.Store(true)
+				return handler(srv, ss)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new grpc service server: %v", err)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	s.listener = listener
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("start grpc server: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	stream, err := grpc_health_v1.NewHealthClient(conn).Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("watch health: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receive health status: %v", err)
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("health status = %v, want SERVING", resp.GetStatus())
+	}
+	if !streamed.Load() {
+		t.Fatal("expected custom stream interceptor to run")
+	}
+}
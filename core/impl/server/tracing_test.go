@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/iconnor-code/cogo/core"
+	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
+	"github.com/iconnor-code/cogo/core/impl/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func installTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := tracing.NewProvider(context.Background(), &cogoconfig.Config{},
+		tracing.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)))
+	if err != nil {
+		t.Fatalf("new tracing provider: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close()
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+var pingServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cogo.test.Ping",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Ping",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(context.Context, any) (any, error) { return &emptypb.Empty{}, nil }
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cogo.test.Ping/Ping"}, handler)
+		},
+	}},
+}
+
+func TestGrpcServiceServerPropagatesTraceContext(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	var srvCtxTraceID any
+	config := &cogoconfig.Config{Config: core.Config{GRPC: core.GRPCConfig{Listen: "bufconn"}}}
+	s, err := NewGrpcServiceServer(config, &testLogger{}, GrpcServiceOption{
+		PublicMethods: []string{"/cogo.test.Ping/Ping"},
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				srvCtx, _ := core.SrvCtxFromContext(ctx)
+				srvCtxTraceID, _ = srvCtx.GetField(core.TraceIDKey)
+				return handler(ctx, req)
+			},
+		},
+		RegisterServices: func(baseServer *grpc.Server) error {
+			baseServer.RegisterService(&pingServiceDesc, struct{}{})
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("new grpc service server: %v", err)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	s.listener = listener
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("start grpc server: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "caller")
+	if err := conn.Invoke(ctx, "/cogo.test.Ping/Ping", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		t.Fatalf("invoke ping: %v", err)
+	}
+	parent.End()
+
+	wantTraceID := parent.SpanContext().TraceID()
+	if srvCtxTraceID != wantTraceID.String() {
+		t.Fatalf("srvctx trace id = %v, want %s", srvCtxTraceID, wantTraceID)
+	}
+	var serverSpan bool
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == trace.SpanKindServer && span.Name == "cogo.test.Ping/Ping" {
+			serverSpan = span.SpanContext.TraceID() == wantTraceID
+		}
+	}
+	if !serverSpan {
+		t.Fatalf("expected server span in caller trace, got %d spans", len(exporter.GetSpans()))
+	}
+}
+
+func TestGatewayMuxContinuesTraceparent(t *testing.T) {
+	installTestTracing(t)
+
+	var got trace.SpanContext
+	config := &cogoconfig.Config{Config: core.Config{GRPC: core.GRPCConfig{Listen: ":9090"}}}
+	mux, err := NewGatewayMux(context.Background(), config, func(_ context.Context, mux *runtime.ServeMux, _ string, _ []grpc.DialOption) error {
+		return mux.HandlePath(http.MethodGet, "/v1/ping", func(_ http.ResponseWriter, r *http.Request, _ map[string]string) {
+			got = trace.SpanContextFromContext(r.Context())
+		})
+	})
+	if err != nil {
+		t.Fatalf("new gateway mux: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/ping", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("gateway trace id = %s, want traceparent trace id", got.TraceID())
+	}
+	if got.SpanID().String() == "00f067aa0ba902b7" {
+		t.Fatal("expected gateway to start a child span")
+	}
+}
+
+func TestGrpcServiceServerInstallsTracingFromConfig(t *testing.T) {
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	config := &cogoconfig.Config{Config: core.Config{
+		GRPC:    core.GRPCConfig{Listen: "bufconn"},
+		Tracing: core.TracingConfig{Exporter: "stdout"},
+	}}
+	s, err := NewGrpcServiceServer(config, &testLogger{}, GrpcServiceOption{})
+	if err != nil {
+		t.Fatalf("new grpc service server: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Fatalf("global tracer provider = %T, want the SDK provider built from config", otel.GetTracerProvider())
+	}
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Contains(fields, "traceparent") {
+		t.Fatalf("propagator fields = %v, want traceparent", fields)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
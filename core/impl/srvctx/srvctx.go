@@ -48,25 +48,79 @@ func (u *UserInfo) GetIsAdmin() bool {
 	return u.IsAdmin
 }
 
+type PeerIdentity struct {
+	CommonName  string   `json:"common_name"`
+	DNSNames    []string `json:"dns_names"`
+	URIs        []string `json:"uris"`
+	Fingerprint string   `json:"fingerprint"`
+}
+
+func (p *PeerIdentity) GetCommonName() string {
+	return p.CommonName
+}
+func (p *PeerIdentity) GetDNSNames() []string {
+	return p.DNSNames
+}
+func (p *PeerIdentity) GetURIs() []string {
+	return p.URIs
+}
+func (p *PeerIdentity) GetFingerprint() string {
+	return p.Fingerprint
+}
+
 type SrvCtx struct {
-	mu       sync.RWMutex
+	mu sync.RWMutex
+	// base is the request logger given to NewSrvCtx; logger adds the biz
+	// and user fields to it once they are known.
+	base     core.ILogger
 	logger   core.ILogger
 	bizInfo  core.IBizInfo
 	userInfo core.IUserInfo
+	peer     core.IPeerIdentity
 	ext      map[core.SrvCtxKey]any
 }
 
 func NewSrvCtx(logger core.ILogger) *SrvCtx {
 	return &SrvCtx{
+		base:   logger,
 		logger: logger,
 		ext:    make(map[core.SrvCtxKey]any),
 	}
 }
 
+// Logger returns the request logger, carrying the biz and user fields set
+// so far in addition to the fields of the logger given to NewSrvCtx.
 func (s *SrvCtx) Logger() core.ILogger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.logger
 }
 
+// refreshLogger rebuilds the request logger from base; callers hold mu.
+func (s *SrvCtx) refreshLogger() {
+	if s.base == nil {
+		return
+	}
+	var fields []any
+	if s.bizInfo != nil {
+		fields = append(fields,
+			"biz_id", s.bizInfo.GetBizID(),
+			"biz_name", s.bizInfo.GetBizName(),
+		)
+		if caller := s.bizInfo.GetCallerBizName(); caller != "" {
+			fields = append(fields, "caller_biz_name", caller)
+		}
+	}
+	if s.userInfo != nil {
+		fields = append(fields, "user_id", s.userInfo.GetUserID())
+	}
+	if len(fields) == 0 {
+		s.logger = s.base
+		return
+	}
+	s.logger = s.base.With(fields...)
+}
+
 func (s *SrvCtx) SetField(key core.SrvCtxKey, value any) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -84,6 +138,7 @@ func (s *SrvCtx) SetBizInfo(bizInfo core.IBizInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.bizInfo = bizInfo
+	s.refreshLogger()
 }
 
 func (s *SrvCtx) GetBizInfo() core.IBizInfo {
@@ -96,6 +151,7 @@ func (s *SrvCtx) SetUserInfo(userInfo core.IUserInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.userInfo = userInfo
+	s.refreshLogger()
 }
 
 func (s *SrvCtx) GetUserInfo() core.IUserInfo {
@@ -103,3 +159,15 @@ func (s *SrvCtx) GetUserInfo() core.IUserInfo {
 	defer s.mu.RUnlock()
 	return s.userInfo
 }
+
+func (s *SrvCtx) SetPeerIdentity(peer core.IPeerIdentity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peer = peer
+}
+
+func (s *SrvCtx) GetPeerIdentity() core.IPeerIdentity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.peer
+}
@@ -1,6 +1,7 @@
 package srvctx
 
 import (
+	"slices"
 	"sync"
 	"testing"
 
@@ -9,14 +10,15 @@ import (
 
 type testLogger struct{}
 
-func (l *testLogger) Log(...any) error       { return nil }
-func (l *testLogger) Debug(string, ...any)   {}
-func (l *testLogger) Info(string, ...any)    {}
-func (l *testLogger) Warn(string, ...any)    {}
-func (l *testLogger) Error(string, ...any)   {}
-func (l *testLogger) Fatal(string, ...any)   {}
-func (l *testLogger) Panic(string, ...any)   {}
-func (l *testLogger) AddGlobalFields(...any) {}
+func (l *testLogger) Log(...any) error         { return nil }
+func (l *testLogger) Debug(string, ...any)     {}
+func (l *testLogger) Info(string, ...any)      {}
+func (l *testLogger) Warn(string, ...any)      {}
+func (l *testLogger) Error(string, ...any)     {}
+func (l *testLogger) Fatal(string, ...any)     {}
+func (l *testLogger) Panic(string, ...any)     {}
+func (l *testLogger) AddGlobalFields(...any)   {}
+func (l *testLogger) With(...any) core.ILogger { return l }
 
 func TestSrvCtxSetGetField(t *testing.T) {
 	s := NewSrvCtx(&testLogger{})
@@ -67,3 +69,40 @@ func TestSrvCtxBizAndUserInfo(t *testing.T) {
 		t.Fatalf("unexpected admin flag")
 	}
 }
+
+type fieldLogger struct {
+	testLogger
+	fields []any
+}
+
+func (l *fieldLogger) With(fields ...any) core.ILogger {
+	return &fieldLogger{fields: append(append([]any(nil), l.fields...), fields...)}
+}
+
+// keys returns the keys of the key/value pairs in fields.
+func (l *fieldLogger) keys() []string {
+	keys := make([]string, 0, len(l.fields)/2)
+	for i := 0; i+1 < len(l.fields); i += 2 {
+		keys = append(keys, l.fields[i].(string))
+	}
+	return keys
+}
+
+func TestSrvCtxLoggerCarriesBizAndUserFields(t *testing.T) {
+	s := NewSrvCtx(&fieldLogger{fields: []any{"method", "/test.Service/Call"}})
+	s.SetBizInfo(&BizInfo{BizID: 1, BizName: "biz"})
+	s.SetUserInfo(&UserInfo{UserID: 2})
+	s.SetUserInfo(&UserInfo{UserID: 3})
+
+	logger, ok := s.Logger().(*fieldLogger)
+	if !ok {
+		t.Fatalf("logger = %T, want child fieldLogger", s.Logger())
+	}
+	want := []string{"method", "biz_id", "biz_name", "user_id"}
+	if got := logger.keys(); !slices.Equal(got, want) {
+		t.Fatalf("logger fields = %v, want %v", got, want)
+	}
+	if got := logger.fields[7]; got != uint32(3) {
+		t.Fatalf("user_id = %d, want latest user 3", got)
+	}
+}
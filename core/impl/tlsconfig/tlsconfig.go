@@ -0,0 +1,297 @@
+// Package tlsconfig builds the gRPC transport credentials of the tls config
+// section. Certificate and CA files are checked for changes at most once per
+// reload interval, during handshakes, so rotated files apply to new
+// connections without a restart.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// ServerCredentials returns the credentials of the gRPC server, or insecure
+// credentials when tls.enable is off. logger may be nil.
+func ServerCredentials(conf core.TLSConfig, logger core.ILogger) (credentials.TransportCredentials, error) {
+	if !conf.Enable {
+		return insecure.NewCredentials(), nil
+	}
+	clientAuth, err := clientAuthType(conf.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	files, err := newReloader(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	if files.current.cert == nil {
+		return nil, errors.New("tls cert_file and key_file are required for the grpc server")
+	}
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			material := files.get()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*material.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    material.pool,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}), nil
+}
+
+// ClientCredentials returns the credentials of outgoing gRPC connections,
+// or insecure credentials when tls.enable is off. The certificate is sent
+// to servers that ask for one. logger may be nil.
+func ClientCredentials(conf core.TLSConfig, logger core.ILogger) (credentials.TransportCredentials, error) {
+	if !conf.Enable {
+		return insecure.NewCredentials(), nil
+	}
+	files, err := newReloader(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := files.get().cert; cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}
+	return &clientCredentials{
+		TransportCredentials: credentials.NewTLS(config),
+		config:               config,
+		serverName:           strings.TrimSpace(conf.ServerName),
+		files:                files,
+	}, nil
+}
+
+// clientCredentials fixes the name the server certificate must match before
+// each handshake: tls.server_name, or else the host of the dialed authority,
+// which may be an IP address matched against IP SANs.
+type clientCredentials struct {
+	credentials.TransportCredentials
+	config     *tls.Config
+	serverName string
+	files      *reloader
+}
+
+func (c *clientCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	name := c.serverName
+	if name == "" {
+		host, _, err := net.SplitHostPort(authority)
+		if err != nil {
+			host = authority
+		}
+		name = host
+	}
+	if name == "" {
+		return nil, nil, errors.New("tls: no server name to verify the server certificate against, set tls.server_name")
+	}
+	config := c.config.Clone()
+	config.ServerName = name
+	if c.files.get().pool != nil {
+		// RootCAs cannot change once the handshake config is built, so the
+		// server certificate is verified here against the current CA.
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyServer(state, name, c.files.get().pool)
+		}
+	}
+	return credentials.NewTLS(config).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *clientCredentials) Clone() credentials.TransportCredentials {
+	return &clientCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		config:               c.config,
+		serverName:           c.serverName,
+		files:                c.files,
+	}
+}
+
+// verifyServer checks the certificate chain against roots and the leaf
+// against name, a DNS name or an IP address.
+func verifyServer(state tls.ConnectionState, name string, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tls: server sent no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func clientAuthType(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unsupported tls client_auth %q", mode)
+}
+
+type material struct {
+	// cert is nil without cert_file and key_file.
+	cert *tls.Certificate
+	// pool is nil without ca_file, meaning the system roots.
+	pool *x509.CertPool
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloader keeps the certificate and CA of a TLSConfig and loads them again
+// when their files change.
+type reloader struct {
+	conf     core.TLSConfig
+	interval time.Duration
+	logger   core.ILogger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	current *material
+	stamps  map[string]fileStamp
+	checked time.Time
+}
+
+func newReloader(conf core.TLSConfig, logger core.ILogger) (*reloader, error) {
+	r := &reloader{conf: conf, interval: defaultReloadInterval, logger: logger, now: time.Now}
+	if conf.ReloadInterval > 0 {
+		r.interval = conf.ReloadInterval.Duration()
+	}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current, r.stamps, r.checked = current, stamps, r.now()
+	return r, nil
+}
+
+// get returns the current material, first loading the files again if the
+// reload interval has passed and any of them changed. A file that fails to
+// load keeps the previous material in use.
+func (r *reloader) get() *material {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if now.Sub(r.checked) < r.interval {
+		return r.current
+	}
+	r.checked = now
+	stamps, err := r.stat()
+	if err == nil && sameStamps(stamps, r.stamps) {
+		return r.current
+	}
+	var current *material
+	if err == nil {
+		current, err = r.load()
+	}
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Warn("reloading tls files failed, keeping the previous certificates", "error", err)
+		}
+		return r.current
+	}
+	r.current, r.stamps = current, stamps
+	if r.logger != nil {
+		r.logger.Info("tls certificates reloaded", "cert_file", r.conf.CertFile, "ca_file", r.conf.CAFile)
+	}
+	return r.current
+}
+
+func (r *reloader) files() []string {
+	var files []string
+	for _, file := range []string{r.conf.CertFile, r.conf.KeyFile, r.conf.CAFile} {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *reloader) stat() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, file := range r.files() {
+		// Stat follows symlinks, so Kubernetes secret updates, which swap
+		// a symlink, show up as a new modification time.
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat tls file: %w", err)
+		}
+		stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *reloader) load() (*material, error) {
+	loaded := &material{}
+	certFile, keyFile := strings.TrimSpace(r.conf.CertFile), strings.TrimSpace(r.conf.KeyFile)
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls key pair: %w", err)
+		}
+		loaded.cert = &cert
+	}
+	if caFile := strings.TrimSpace(r.conf.CAFile); caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("tls ca_file %s contains no PEM certificates", caFile)
+		}
+		loaded.pool = pool
+	}
+	return loaded, nil
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, stamp := range a {
+		if other, ok := b[file]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,327 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for name, a DNS name or an IP address, signed
+// by ca to dir and returns the cert and key paths.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveHealth starts a health server on 127.0.0.1 with the server config
+// and returns a function that calls it with a client config.
+func serveHealth(t *testing.T, conf core.TLSConfig, interceptor grpc.UnaryServerInterceptor) func(core.TLSConfig) error {
+	t.Helper()
+	serverCreds, err := ServerCredentials(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []grpc.ServerOption{grpc.Creds(serverCreds)}
+	if interceptor != nil {
+		opts = append(opts, grpc.UnaryInterceptor(interceptor))
+	}
+	server := grpc.NewServer(opts...)
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return func(conf core.TLSConfig) error {
+		creds, err := ClientCredentials(conf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(creds))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		return err
+	}
+}
+
+func TestMutualTLSExposesVerifiedClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, dir, "account.internal", 2)
+	clientCert, clientKey := ca.issue(t, dir, "order.internal", 3)
+
+	var peerCN string
+	check := serveHealth(t, core.TLSConfig{
+		Enable: true, CAFile: caFile, CertFile: serverCert, KeyFile: serverKey, ClientAuth: "require_and_verify",
+	}, func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+				peerCN = info.State.PeerCertificates[0].Subject.CommonName
+			}
+		}
+		return handler(ctx, req)
+	})
+
+	if err := check(core.TLSConfig{
+		Enable: true, CAFile: caFile, CertFile: clientCert, KeyFile: clientKey, ServerName: "account.internal",
+	}); err != nil {
+		t.Fatalf("mTLS call: %v", err)
+	}
+	if peerCN != "order.internal" {
+		t.Fatalf("peer common name = %q, want order.internal", peerCN)
+	}
+
+	if err := check(core.TLSConfig{Enable: true, CAFile: caFile, ServerName: "account.internal"}); err == nil {
+		t.Fatal("call without a client certificate succeeded")
+	}
+	if err := check(core.TLSConfig{
+		Enable: true, CAFile: caFile, CertFile: clientCert, KeyFile: clientKey, ServerName: "billing.internal",
+	}); err == nil {
+		t.Fatal("call with a mismatched server name succeeded")
+	}
+}
+
+func TestClientVerifiesIPTargetsAgainstIPSANs(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	client := core.TLSConfig{Enable: true, CAFile: caFile}
+
+	otherCert, otherKey := ca.issue(t, dir, "other.example", 20)
+	check := serveHealth(t, core.TLSConfig{Enable: true, CertFile: otherCert, KeyFile: otherKey}, nil)
+	if err := check(client); err == nil {
+		t.Fatal("dialing 127.0.0.1 accepted a certificate for other.example")
+	}
+
+	ipCert, ipKey := ca.issue(t, dir, "127.0.0.1", 21)
+	check = serveHealth(t, core.TLSConfig{Enable: true, CertFile: ipCert, KeyFile: ipKey}, nil)
+	if err := check(client); err != nil {
+		t.Fatalf("dialing 127.0.0.1 with a certificate for its IP: %v", err)
+	}
+}
+
+func TestReloaderPicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "account.internal", 10)
+
+	r, err := newReloader(core.TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: core.Duration(time.Minute)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Now()
+	r.now = func() time.Time { return clock }
+	serial := func() int64 {
+		leaf, err := x509.ParseCertificate(r.get().cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.SerialNumber.Int64()
+	}
+
+	ca.issue(t, dir, "account.internal", 11)
+	// Force a different modification time on filesystems with coarse ones.
+	later := time.Now().Add(time.Second)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := serial(); got != 10 {
+		t.Fatalf("serial = %d within the reload interval, want 10", got)
+	}
+	clock = clock.Add(time.Minute)
+	if got := serial(); got != 11 {
+		t.Fatalf("serial = %d after the reload interval, want 11", got)
+	}
+
+	// A broken file keeps the previous certificate.
+	writeFile(t, certFile, []byte("not a certificate"))
+	clock = clock.Add(time.Minute)
+	if got := serial(); got != 11 {
+		t.Fatalf("serial = %d after a broken rotation, want 11", got)
+	}
+}
+
+func TestClientHandshakesRaceWithReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, dir, "account.internal", 30)
+	serverCreds, err := ServerCredentials(core.TLSConfig{Enable: true, CertFile: serverCert, KeyFile: serverKey}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(serverCreds))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	creds, err := ClientCredentials(core.TLSConfig{
+		Enable: true, CAFile: caFile, ServerName: "account.internal", ReloadInterval: core.Duration(time.Nanosecond),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	rotated := make(chan struct{})
+	go func() {
+		defer close(rotated)
+		for i := 1; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Each new modification time makes the next handshake reload.
+			at := time.Now().Add(time.Duration(i) * time.Second)
+			_ = os.Chtimes(caFile, at, at)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				rawConn, err := net.Dial("tcp", listener.Addr().String())
+				if err != nil {
+					errs <- err
+					return
+				}
+				conn, _, err := creds.ClientHandshake(context.Background(), listener.Addr().String(), rawConn)
+				_ = rawConn.Close()
+				if err != nil {
+					errs <- err
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-rotated
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("handshake during reload: %v", err)
+		}
+	}
+}
+
+func TestDisabledTLSUsesInsecureCredentials(t *testing.T) {
+	creds, err := ServerCredentials(core.TLSConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protocol := creds.Info().SecurityProtocol; protocol != "insecure" {
+		t.Fatalf("security protocol = %q, want insecure", protocol)
+	}
+	if _, err := ServerCredentials(core.TLSConfig{Enable: true}, nil); err == nil {
+		t.Fatal("server credentials without a certificate succeeded")
+	}
+}
@@ -0,0 +1,155 @@
+// Package tracing configures the process-wide OpenTelemetry tracer provider
+// used by the gRPC server, the gateway mux and rpcclient connections.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/iconnor-code/cogo/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// InstrumentationName is the tracer name used by cogo instrumentation.
+const InstrumentationName = "github.com/iconnor-code/cogo"
+
+// Provider owns the SDK tracer provider installed as the global provider.
+// Close flushes pending spans and must be called by the process lifecycle
+// owner, typically by passing the provider in GrpcServiceOption.Closers.
+type Provider struct {
+	tracerProvider *sdktrace.TracerProvider
+}
+
+type Option func(*options) error
+
+type options struct {
+	processors []sdktrace.SpanProcessor
+}
+
+// WithSpanProcessor adds a span processor next to the configured exporter.
+// Tests use it with an in-memory exporter to assert on recorded spans.
+func WithSpanProcessor(processor sdktrace.SpanProcessor) Option {
+	return func(opts *options) error {
+		if processor == nil {
+			return errors.New("span processor is required")
+		}
+		opts.processors = append(opts.processors, processor)
+		return nil
+	}
+}
+
+// NewProvider builds the exporter selected by TracingConfig and installs the
+// resulting provider and the W3C trace context propagator globally. With the
+// "none" exporter and no extra processors, the global no-op provider is kept
+// so incoming trace context is still forwarded to downstream calls.
+func NewProvider(ctx context.Context, config core.IConfig, opts ...Option) (*Provider, error) {
+	if config == nil {
+		return nil, errors.New("tracing config is required")
+	}
+	o := options{}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	tracingConf := config.GetTracing()
+	exporter, err := newExporter(ctx, tracingConf)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil && len(o.processors) == 0 {
+		return &Provider{}, nil
+	}
+
+	ratio := tracingConf.SampleRatio
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("tracing sample ratio must be between 0 and 1: %v", ratio)
+	}
+	if ratio == 0 {
+		ratio = 1
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", ServiceName(config)),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	for _, processor := range o.processors {
+		providerOpts = append(providerOpts, sdktrace.WithSpanProcessor(processor))
+	}
+	tracerProvider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(tracerProvider)
+	return &Provider{tracerProvider: tracerProvider}, nil
+}
+
+func newExporter(ctx context.Context, conf core.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(conf.Exporter)) {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, cerrs.Wrap(err)
+		}
+		return exporter, nil
+	case "otlp":
+		if strings.TrimSpace(conf.Endpoint) == "" {
+			return nil, errors.New("tracing endpoint is required for otlp exporter")
+		}
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, cerrs.Wrap(err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", conf.Exporter)
+	}
+}
+
+// ServiceName is the service.name resource attribute of the service:
+// tracing.service_name, then registry.name, then biz_name.
+func ServiceName(config core.IConfig) string {
+	if name := strings.TrimSpace(config.GetTracing().ServiceName); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(config.GetRegistry().Name); name != "" {
+		return name
+	}
+	return config.GetBizName()
+}
+
+// Shutdown flushes pending spans and stops the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.tracerProvider == nil {
+		return nil
+	}
+	return p.tracerProvider.Shutdown(ctx)
+}
+
+// Close implements io.Closer so the provider can be owned by a server.
+func (p *Provider) Close() error {
+	return p.Shutdown(context.Background())
+}
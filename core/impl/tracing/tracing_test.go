@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestNewProviderRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracing core.TracingConfig
+		wantErr string
+	}{
+		{name: "unknown exporter", tracing: core.TracingConfig{Exporter: "zipkin"}, wantErr: "unsupported"},
+		{name: "otlp without endpoint", tracing: core.TracingConfig{Exporter: "otlp"}, wantErr: "endpoint"},
+		{name: "sample ratio", tracing: core.TracingConfig{Exporter: "stdout", SampleRatio: 2}, wantErr: "sample ratio"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProvider(context.Background(), &cogoconfig.Config{Config: core.Config{Tracing: tt.tracing}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewProviderWithoutExporterKeepsNoopProvider(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	provider, err := NewProvider(context.Background(), &cogoconfig.Config{Config: core.Config{Tracing: core.TracingConfig{Exporter: "none"}}})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Fatal("expected global tracer provider to stay unchanged")
+	}
+	if err := provider.Close(); err != nil {
+		t.Fatalf("close provider: %v", err)
+	}
+}
@@ -6,3 +6,16 @@ type IRegistry interface {
 	Register(ctx context.Context) error
 	DeRegister(ctx context.Context) error
 }
+
+// EtcdServicePrefix is the etcd key prefix under which the instances of
+// service are registered, one key per instance holding a ServiceEndpoint.
+func EtcdServicePrefix(service string) string {
+	return "/services/" + service + "/"
+}
+
+// ServiceEndpoint is the JSON value of an instance key registered in etcd.
+type ServiceEndpoint struct {
+	Address  string            `json:"address"`
+	Port     int               `json:"port"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
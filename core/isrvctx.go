@@ -8,6 +8,17 @@ type SrvCtxKey string
 
 const SrvCtx SrvCtxKey = "srvctx"
 
+// Well-known ISrvCtx field keys populated by the framework interceptors.
+const (
+	TraceIDKey   SrvCtxKey = "trace_id"
+	SpanIDKey    SrvCtxKey = "span_id"
+	RequestIDKey SrvCtxKey = "request_id"
+)
+
+// RequestIDHeader is the metadata key and HTTP header carrying the request
+// ID between the gateway, services and their callers.
+const RequestIDHeader = "x-request-id"
+
 func SrvCtxFromContext(ctx context.Context) (ISrvCtx, bool) {
 	if ctx == nil {
 		return nil, false
@@ -29,6 +40,17 @@ type IUserInfo interface {
 	GetIsAdmin() bool
 }
 
+// IPeerIdentity describes the verified client certificate of an mTLS
+// connection.
+type IPeerIdentity interface {
+	GetCommonName() string
+	GetDNSNames() []string
+	// GetURIs returns the URI SANs, such as SPIFFE IDs.
+	GetURIs() []string
+	// GetFingerprint returns the hex SHA-256 of the certificate.
+	GetFingerprint() string
+}
+
 type ISrvCtx interface {
 	Logger() ILogger
 	SetField(key SrvCtxKey, value any)
@@ -37,4 +59,8 @@ type ISrvCtx interface {
 	GetBizInfo() IBizInfo
 	SetUserInfo(userInfo IUserInfo)
 	GetUserInfo() IUserInfo
+	SetPeerIdentity(peer IPeerIdentity)
+	// GetPeerIdentity returns nil unless the caller presented a client
+	// certificate that the server verified.
+	GetPeerIdentity() IPeerIdentity
 }
@@ -20,30 +20,46 @@ type BizInfoConfig interface {
 
 func BizInfoInterceptor(config BizInfoConfig) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		srvCtx, ok := core.SrvCtxFromContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Internal, "srvctx is required")
+		if err := setBizInfo(ctx, config); err != nil {
+			return nil, err
 		}
-		bizInfo := &srvctx.BizInfo{}
-		bizInfo.BizID = int32(config.GetBizID())
-		bizInfo.BizName = config.GetBizName()
+		return handler(ctx, req)
+	}
+}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.InvalidArgument, "metadata is required")
+func BizInfoStreamInterceptor(config BizInfoConfig) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := setBizInfo(ss.Context(), config); err != nil {
+			return err
 		}
+		return handler(srv, ss)
+	}
+}
 
-		for _, bizID := range md.Get("biz_id") {
-			originalBizID, err := strconv.ParseInt(bizID, 10, 32)
-			if err != nil {
-				return nil, status.Errorf(codes.InvalidArgument, "biz_id is invalid")
-			}
-			bizInfo.OriginalBizID = append(bizInfo.OriginalBizID, int32(originalBizID))
-		}
+func setBizInfo(ctx context.Context, config BizInfoConfig) error {
+	srvCtx, ok := core.SrvCtxFromContext(ctx)
+	if !ok {
+		return status.Errorf(codes.Internal, "srvctx is required")
+	}
+	bizInfo := &srvctx.BizInfo{}
+	bizInfo.BizID = int32(config.GetBizID())
+	bizInfo.BizName = config.GetBizName()
 
-		bizInfo.OriginalBizName = md.Get("biz_name")
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "metadata is required")
+	}
 
-		srvCtx.SetBizInfo(bizInfo)
-		return handler(ctx, req)
+	for _, bizID := range md.Get("biz_id") {
+		originalBizID, err := strconv.ParseInt(bizID, 10, 32)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "biz_id is invalid")
+		}
+		bizInfo.OriginalBizID = append(bizInfo.OriginalBizID, int32(originalBizID))
 	}
+
+	bizInfo.OriginalBizName = md.Get("biz_name")
+
+	srvCtx.SetBizInfo(bizInfo)
+	return nil
 }
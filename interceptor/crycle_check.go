@@ -5,7 +5,6 @@ import (
 	"slices"
 
 	"github.com/iconnor-code/cogo/core"
-	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -16,25 +15,41 @@ const CallerMethodsKey = "caller_methods"
 
 func CycleCheckInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		srvCtx, ok := core.SrvCtxFromContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Internal, "srvctx is required")
+		ctx, err := checkCycle(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
 		}
-		logger := srvCtx.Logger()
+		return handler(ctx, req)
+	}
+}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.InvalidArgument, "metadata is required")
+func CycleCheckStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := checkCycle(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
 		}
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
 
-		callerMethods := md.Get(CallerMethodsKey)
-		if slices.Contains(callerMethods, info.FullMethod) {
-			logger.Error("cycle call detected", zap.Strings("caller methods", callerMethods), zap.String("current method", info.FullMethod))
-			return nil, status.Errorf(codes.Aborted, "cycle call detected!")
-		}
+func checkCycle(ctx context.Context, method string) (context.Context, error) {
+	srvCtx, ok := core.SrvCtxFromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "srvctx is required")
+	}
+	logger := srvCtx.Logger()
 
-		ctx = metadata.AppendToOutgoingContext(ctx, CallerMethodsKey, info.FullMethod)
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "metadata is required")
+	}
 
-		return handler(ctx, req)
+	callerMethods := md.Get(CallerMethodsKey)
+	if slices.Contains(callerMethods, method) {
+		logger.Error("cycle call detected", "caller methods", callerMethods, "current method", method)
+		return nil, status.Errorf(codes.Aborted, "cycle call detected!")
 	}
+
+	return metadata.AppendToOutgoingContext(ctx, CallerMethodsKey, method), nil
 }
@@ -17,19 +17,33 @@ func ErrorInterceptor() grpc.UnaryServerInterceptor {
 		if err == nil {
 			return resp, nil
 		}
-		if _, ok := status.FromError(err); ok {
-			return nil, err
+		return nil, transportError(err)
+	}
+}
+
+// ErrorStreamInterceptor is the streaming counterpart of ErrorInterceptor.
+func ErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return transportError(err)
 		}
+		return nil
+	}
+}
+
+func transportError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
 
-		var customErr *cerrs.CError
-		if errors.As(err, &customErr) {
-			if customErr.Kind() == cerrs.KindInternal {
-				return nil, status.Error(codes.Internal, "internal error occurred")
-			}
-			return nil, status.Error(grpcCodeForKind(customErr.Kind()), customErr.PublicMessage())
+	var customErr *cerrs.CError
+	if errors.As(err, &customErr) {
+		if customErr.Kind() == cerrs.KindInternal {
+			return status.Error(codes.Internal, "internal error occurred")
 		}
-		return nil, status.Error(codes.Internal, "internal error occurred")
+		return status.Error(grpcCodeForKind(customErr.Kind()), customErr.PublicMessage())
 	}
+	return status.Error(codes.Internal, "internal error occurred")
 }
 
 func grpcCodeForKind(kind cerrs.Kind) codes.Code {
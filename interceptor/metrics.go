@@ -0,0 +1,134 @@
+package interceptor
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ServerMetrics holds the per-method request counter and latency histogram
+// recorded by MetricsInterceptor and MetricsStreamInterceptor, and the rate
+// limit decisions of RateLimitInterceptor.
+type ServerMetrics struct {
+	handled   *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	rateLimit *prometheus.CounterVec
+	callers   func() []string
+}
+
+// otherCaller is the caller label of callers outside the known set.
+const otherCaller = "other"
+
+type ServerMetricsOption func(*ServerMetrics)
+
+// WithKnownCallers labels requests from the caller biz names returned by
+// callers with that name. Callers choose the name they send, so every
+// other one is labelled "other" to keep the number of series bounded.
+// callers is read on every request so it follows config reloads.
+func WithKnownCallers(callers func() []string) ServerMetricsOption {
+	return func(m *ServerMetrics) {
+		m.callers = callers
+	}
+}
+
+// NewServerMetrics registers the server metrics under namespace, usually
+// MetricsConfig.Prefix. A nil registerer uses the default Prometheus registry.
+// Without WithKnownCallers, every caller is labelled "other".
+func NewServerMetrics(namespace string, registerer prometheus.Registerer, options ...ServerMetricsOption) (*ServerMetrics, error) {
+	labels := []string{"method", "code", "caller"}
+	handled, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handled_total",
+		Help:      "Total number of RPCs completed on the server, regardless of success or failure.",
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := utils.RegisterCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handling_seconds",
+		Help:      "Latency of RPCs handled by the server.",
+		Buckets:   prometheus.DefBuckets,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	rateLimit, err := utils.RegisterCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_rate_limit_total",
+		Help:      "Rate limit decisions by method, rule key and result (allowed, limited or error).",
+	}, []string{"method", "key", "result"}))
+	if err != nil {
+		return nil, err
+	}
+	metrics := &ServerMetrics{handled: handled, duration: duration, rateLimit: rateLimit}
+	for _, option := range options {
+		option(metrics)
+	}
+	return metrics, nil
+}
+
+func MetricsInterceptor(metrics *ServerMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.observe(ctx, info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+func MetricsStreamInterceptor(metrics *ServerMetrics) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		metrics.observe(ss.Context(), info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
+
+func (m *ServerMetrics) observe(ctx context.Context, method string, duration time.Duration, err error) {
+	labels := prometheus.Labels{
+		"method": method,
+		"code":   status.Code(err).String(),
+		"caller": m.caller(ctx),
+	}
+	m.handled.With(labels).Inc()
+	m.duration.With(labels).Observe(duration.Seconds())
+}
+
+func (m *ServerMetrics) observeRateLimit(method, key, result string) {
+	if m == nil {
+		return
+	}
+	m.rateLimit.With(prometheus.Labels{"method": method, "key": key, "result": result}).Inc()
+}
+
+// caller returns the caller label: empty for requests without a caller,
+// the name for known callers and "other" for the rest.
+func (m *ServerMetrics) caller(ctx context.Context) string {
+	name := callerBizName(ctx)
+	if name == "" || (m.callers != nil && slices.Contains(m.callers(), name)) {
+		return name
+	}
+	return otherCaller
+}
+
+// callerBizName reads the caller recorded by BizInfoInterceptor. Metrics run
+// outside that interceptor, so the value is only available after the handler.
+func callerBizName(ctx context.Context) string {
+	srvCtx, ok := core.SrvCtxFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	bizInfo := srvCtx.GetBizInfo()
+	if bizInfo == nil {
+		return ""
+	}
+	return bizInfo.GetCallerBizName()
+}
@@ -0,0 +1,66 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsInterceptorRecordsMethodCodeAndCaller(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewServerMetrics("account", registry, WithKnownCallers(func() []string { return []string{"gateway"} }))
+	if err != nil {
+		t.Fatalf("new server metrics: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/account.AuthService/Login"}
+	call := func(caller string) {
+		serviceContext := srvctx.NewSrvCtx(&testLogger{})
+		ctx := context.WithValue(context.Background(), core.SrvCtx, serviceContext)
+		_, _ = MetricsInterceptor(metrics)(ctx, nil, info, func(context.Context, any) (any, error) {
+			serviceContext.SetBizInfo(&srvctx.BizInfo{OriginalBizName: []string{caller}})
+			return nil, status.Error(codes.NotFound, "missing")
+		})
+	}
+
+	call("gateway")
+	got := testutil.ToFloat64(metrics.handled.WithLabelValues("/account.AuthService/Login", codes.NotFound.String(), "gateway"))
+	if got != 1 {
+		t.Fatalf("handled counter = %v, want 1", got)
+	}
+	if count := testutil.CollectAndCount(registry, "account_grpc_server_handling_seconds"); count != 1 {
+		t.Fatalf("latency series = %d, want 1", count)
+	}
+
+	// Unknown callers share one series however many names they send.
+	call("unknown-a")
+	call("unknown-b")
+	got = testutil.ToFloat64(metrics.handled.WithLabelValues("/account.AuthService/Login", codes.NotFound.String(), "other"))
+	if got != 2 {
+		t.Fatalf("other caller counter = %v, want 2", got)
+	}
+	if count := testutil.CollectAndCount(registry, "account_grpc_server_handled_total"); count != 2 {
+		t.Fatalf("handled series = %d, want 2", count)
+	}
+}
+
+func TestNewServerMetricsReusesRegisteredCollectors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first, err := NewServerMetrics("account", registry)
+	if err != nil {
+		t.Fatalf("new server metrics: %v", err)
+	}
+	second, err := NewServerMetrics("account", registry)
+	if err != nil {
+		t.Fatalf("new server metrics again: %v", err)
+	}
+	if first.handled != second.handled || first.duration != second.duration {
+		t.Fatal("expected collectors to be shared")
+	}
+}
@@ -0,0 +1,233 @@
+package interceptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	defaultPayloadMaxBytes = 4096
+	maskedPayloadValue     = "[REDACTED]"
+)
+
+// PayloadLogConfig supplies grpc.payload_log and logger.redact, whose key
+// patterns also mask payload fields.
+type PayloadLogConfig interface {
+	GetGRPC() core.GRPCConfig
+	GetLogger() core.LoggerConfig
+}
+
+type RequestLogOption func(*requestLogOptions)
+
+type requestLogOptions struct {
+	payloadConfig PayloadLogConfig
+	maskOption    protoreflect.ExtensionType
+	redactKeys    *redactKeys
+}
+
+// WithPayloadLog logs request and response payloads as configured by
+// grpc.payload_log, read on every call so the setting can be turned on by
+// a config reload.
+func WithPayloadLog(config PayloadLogConfig) RequestLogOption {
+	return func(opts *requestLogOptions) {
+		opts.payloadConfig = config
+		opts.redactKeys = &redactKeys{}
+	}
+}
+
+// WithPayloadMaskOption masks fields carrying the given bool field option,
+// e.g. a custom (acme.sensitive) = true extension. Fields marked with the
+// standard debug_redact option are always masked.
+func WithPayloadMaskOption(option protoreflect.ExtensionType) RequestLogOption {
+	return func(opts *requestLogOptions) {
+		opts.maskOption = option
+	}
+}
+
+// payloadLogEnabled reports whether the payloads of method are logged.
+func payloadLogEnabled(conf core.GRPCPayloadLogConfig, method string) bool {
+	for _, pattern := range conf.Methods {
+		if pattern == method {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return conf.SampleRatio > 0 && rand.Float64() < conf.SampleRatio
+}
+
+// redactKeys caches the key pattern of logger.redact, compiled again when a
+// config reload changes the keys.
+type redactKeys struct {
+	mu      sync.Mutex
+	keys    []string
+	pattern *regexp.Regexp
+}
+
+// get returns the pattern of conf, or nil when redaction is disabled. Keys
+// that do not compile, which the logger also rejects, fall back to the
+// built-in patterns.
+func (c *redactKeys) get(conf core.LoggerRedactConfig) *regexp.Regexp {
+	if conf.Disable {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pattern != nil && slices.Equal(c.keys, conf.Keys) {
+		return c.pattern
+	}
+	pattern, err := conf.KeyPattern()
+	if err != nil {
+		pattern, _ = core.LoggerRedactConfig{}.KeyPattern()
+	}
+	c.keys, c.pattern = slices.Clone(conf.Keys), pattern
+	return pattern
+}
+
+// encodePayload returns the masked protojson form of a message, cut to the
+// configured size. Fields are masked before encoding, so truncation, which
+// turns the payload into a plain string the logger cannot inspect by key,
+// never exposes them. Values other than proto messages are not logged.
+func encodePayload(value any, conf core.GRPCPayloadLogConfig, maskOption protoreflect.ExtensionType, keys *regexp.Regexp) (any, bool) {
+	message, ok := value.(proto.Message)
+	if !ok || !message.ProtoReflect().IsValid() {
+		return nil, false
+	}
+	masked := proto.Clone(message)
+	maskSensitiveFields(masked.ProtoReflect(), maskOption, keys)
+	for _, path := range conf.MaskFields {
+		maskFieldPath(masked.ProtoReflect(), strings.Split(strings.TrimSpace(path), "."))
+	}
+	data, err := protojson.Marshal(masked)
+	if err != nil {
+		return fmt.Sprintf("encoding payload error: %v", err), true
+	}
+	limit := conf.MaxBytes
+	if limit <= 0 {
+		limit = defaultPayloadMaxBytes
+	}
+	if len(data) <= limit {
+		return json.RawMessage(data), true
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated, %d bytes)", data[:cut], len(data)), true
+}
+
+// maskSensitiveFields masks fields marked debug_redact or maskOption, or
+// whose name or JSON name matches keys, in m and its nested messages.
+func maskSensitiveFields(m protoreflect.Message, maskOption protoreflect.ExtensionType, keys *regexp.Regexp) {
+	type populated struct {
+		field protoreflect.FieldDescriptor
+		value protoreflect.Value
+	}
+	var fields []populated
+	m.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fields = append(fields, populated{field: field, value: value})
+		return true
+	})
+	for _, f := range fields {
+		if sensitiveField(f.field, maskOption) || secretFieldName(f.field, keys) {
+			maskField(m, f.field)
+			continue
+		}
+		eachMessage(f.field, f.value, func(nested protoreflect.Message) {
+			maskSensitiveFields(nested, maskOption, keys)
+		})
+	}
+}
+
+func sensitiveField(field protoreflect.FieldDescriptor, maskOption protoreflect.ExtensionType) bool {
+	options, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok || options == nil {
+		return false
+	}
+	if options.GetDebugRedact() {
+		return true
+	}
+	if maskOption == nil || !proto.HasExtension(options, maskOption) {
+		return false
+	}
+	marked, ok := proto.GetExtension(options, maskOption).(bool)
+	return ok && marked
+}
+
+func secretFieldName(field protoreflect.FieldDescriptor, keys *regexp.Regexp) bool {
+	return keys != nil && (keys.MatchString(string(field.Name())) || keys.MatchString(field.JSONName()))
+}
+
+// maskFieldPath masks the field at path, a list of field names or JSON
+// names. Repeated and map fields apply the rest of the path to every
+// element.
+func maskFieldPath(m protoreflect.Message, path []string) {
+	if len(path) == 0 || path[0] == "" {
+		return
+	}
+	fields := m.Descriptor().Fields()
+	field := fields.ByName(protoreflect.Name(path[0]))
+	if field == nil {
+		field = fields.ByJSONName(path[0])
+	}
+	if field == nil || !m.Has(field) {
+		return
+	}
+	if len(path) == 1 {
+		maskField(m, field)
+		return
+	}
+	eachMessage(field, m.Get(field), func(nested protoreflect.Message) {
+		maskFieldPath(nested, path[1:])
+	})
+}
+
+// maskField replaces strings with a marker and clears other values.
+func maskField(m protoreflect.Message, field protoreflect.FieldDescriptor) {
+	switch {
+	case field.IsMap() || field.Kind() != protoreflect.StringKind:
+		m.Clear(field)
+	case field.IsList():
+		list := m.Mutable(field).List()
+		for i := 0; i < list.Len(); i++ {
+			list.Set(i, protoreflect.ValueOfString(maskedPayloadValue))
+		}
+	default:
+		m.Set(field, protoreflect.ValueOfString(maskedPayloadValue))
+	}
+}
+
+// eachMessage calls fn with the message values of a populated field.
+func eachMessage(field protoreflect.FieldDescriptor, value protoreflect.Value, fn func(protoreflect.Message)) {
+	switch {
+	case field.IsMap():
+		if field.MapValue().Message() == nil {
+			return
+		}
+		value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+			fn(v.Message())
+			return true
+		})
+	case field.Message() == nil:
+	case field.IsList():
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			fn(list.Get(i).Message())
+		}
+	default:
+		fn(value.Message())
+	}
+}
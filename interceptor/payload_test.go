@@ -0,0 +1,170 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type payloadConfig struct{ core.GRPCPayloadLogConfig }
+
+func (c payloadConfig) GetGRPC() core.GRPCConfig {
+	return core.GRPCConfig{PayloadLog: c.GRPCPayloadLogConfig}
+}
+
+func (c payloadConfig) GetLogger() core.LoggerConfig { return core.LoggerConfig{} }
+
+type payloadLogger struct {
+	captureLogger
+	fields map[string]any
+}
+
+func (l *payloadLogger) Info(message string, fields ...any) {
+	l.fields = make(map[string]any)
+	for i := 0; i+1 < len(fields); i += 2 {
+		l.fields[fields[i].(string)] = fields[i+1]
+	}
+}
+
+// loginRequestDescriptor describes
+//
+//	message Profile { string phone = 1; string nickname = 2; }
+//	message LoginRequest {
+//	  string account = 1;
+//	  string password = 2 [debug_redact = true];
+//	  Profile profile = 3;
+//	  string old_password = 4;
+//	}
+func loginRequestDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	password := field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	password.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+	profile := field("profile", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	profile.TypeName = proto.String(".test.Profile")
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/login.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Profile"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("phone", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("nickname", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			}},
+			{Name: proto.String("LoginRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("account", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				password,
+				profile,
+				field("old_password", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Messages().ByName("LoginRequest")
+}
+
+func newLoginRequest(t *testing.T, nickname string) *dynamicpb.Message {
+	t.Helper()
+	desc := loginRequestDescriptor(t)
+	req := dynamicpb.NewMessage(desc)
+	req.Set(desc.Fields().ByName("account"), protoreflect.ValueOfString("alice"))
+	req.Set(desc.Fields().ByName("password"), protoreflect.ValueOfString("super-secret-password"))
+	profile := req.Mutable(desc.Fields().ByName("profile")).Message()
+	profile.Set(profile.Descriptor().Fields().ByName("phone"), protoreflect.ValueOfString("13800138000"))
+	profile.Set(profile.Descriptor().Fields().ByName("nickname"), protoreflect.ValueOfString(nickname))
+	return req
+}
+
+func logPayload(t *testing.T, conf core.GRPCPayloadLogConfig, req proto.Message) map[string]any {
+	t.Helper()
+	logger := &payloadLogger{}
+	ctx := context.WithValue(context.Background(), core.SrvCtx, srvctx.NewSrvCtx(logger))
+	interceptor := RequestLogInterceptorWithOptions(WithPayloadLog(payloadConfig{conf}))
+	_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test.AuthService/Login"}, func(_ context.Context, req any) (any, error) {
+		return req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger.fields
+}
+
+func TestRequestLogPayloadMasksFields(t *testing.T) {
+	fields := logPayload(t, core.GRPCPayloadLogConfig{
+		Methods:    []string{"/test.AuthService/*"},
+		MaskFields: []string{"profile.phone"},
+	}, newLoginRequest(t, "ali"))
+
+	for _, key := range []string{"request", "response"} {
+		raw, ok := fields[key].(json.RawMessage)
+		if !ok {
+			t.Fatalf("%s = %#v, want protojson", key, fields[key])
+		}
+		var got map[string]any
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatal(err)
+		}
+		profile := got["profile"].(map[string]any)
+		if got["account"] != "alice" || got["password"] != maskedPayloadValue ||
+			profile["phone"] != maskedPayloadValue || profile["nickname"] != "ali" {
+			t.Fatalf("%s = %s", key, raw)
+		}
+	}
+}
+
+func TestRequestLogPayloadIsTruncatedAndOffByDefault(t *testing.T) {
+	req := newLoginRequest(t, strings.Repeat("长", 100))
+	fields := logPayload(t, core.GRPCPayloadLogConfig{SampleRatio: 1, MaxBytes: 64}, req)
+	got, ok := fields["request"].(string)
+	if !ok || !strings.Contains(got, "...(truncated, ") || strings.Contains(got, "super-secret") {
+		t.Fatalf("request = %#v, want a truncated masked payload", fields["request"])
+	}
+	if !strings.Contains(got, `"alice"`) {
+		t.Fatalf("request = %q, want the payload head", got)
+	}
+
+	fields = logPayload(t, core.GRPCPayloadLogConfig{}, req)
+	if _, ok := fields["request"]; ok {
+		t.Fatalf("payload logged without grpc.payload_log: %#v", fields)
+	}
+}
+
+func TestRequestLogPayloadMasksSecretFieldNamesBeforeTruncating(t *testing.T) {
+	req := newLoginRequest(t, "ali")
+	req.Set(req.Descriptor().Fields().ByName("old_password"), protoreflect.ValueOfString("old-secret-password"))
+	full := logPayload(t, core.GRPCPayloadLogConfig{SampleRatio: 1, MaxBytes: 1 << 20}, req)
+	raw, ok := full["request"].(json.RawMessage)
+	if !ok {
+		t.Fatalf("request = %#v, want protojson", full["request"])
+	}
+
+	// Cut only the closing bytes so the field stays in the truncated text.
+	fields := logPayload(t, core.GRPCPayloadLogConfig{SampleRatio: 1, MaxBytes: len(raw) - 3}, req)
+	got, ok := fields["request"].(string)
+	if !ok || !strings.Contains(got, "...(truncated, ") {
+		t.Fatalf("request = %#v, want a truncated payload", fields["request"])
+	}
+	if strings.Contains(got, "old-secret") || !strings.Contains(got, `"old_password"`) {
+		t.Fatalf("request = %q, want old_password masked", got)
+	}
+}
@@ -0,0 +1,259 @@
+package interceptor
+
+import (
+	"context"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterHeader carries the seconds to wait after ResourceExhausted; the
+// gateway returns it as the HTTP Retry-After header.
+const RetryAfterHeader = "retry-after"
+
+type RateLimitConfig interface {
+	GetGRPC() core.GRPCConfig
+}
+
+// RateLimitBucket is the token bucket named Key, refilled at Rate tokens per
+// second up to Burst.
+type RateLimitBucket struct {
+	Key   string
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter takes one token from every bucket, but only when each of them
+// has one, so a request rejected by one rule leaves the buckets of the other
+// rules untouched. When a bucket is empty it reports the index of the
+// bucket that takes longest to refill and how long that is.
+type RateLimiter interface {
+	Allow(ctx context.Context, buckets []RateLimitBucket) (allowed bool, limited int, retryAfter time.Duration, err error)
+}
+
+// RateLimitInterceptor applies grpc.rate_limit, read on every call so rules
+// follow config reloads. It must run after BizInfoInterceptor and
+// UserInfoInterceptor, which provide the "biz" and "user" keys. Limiter
+// errors are logged and the request is let through. metrics may be nil.
+func RateLimitInterceptor(config RateLimitConfig, limiter RateLimiter, metrics *ServerMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := rateLimit(ctx, config, limiter, metrics, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func RateLimitStreamInterceptor(config RateLimitConfig, limiter RateLimiter, metrics *ServerMetrics) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(ss.Context(), config, limiter, metrics, info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func rateLimit(ctx context.Context, config RateLimitConfig, limiter RateLimiter, metrics *ServerMetrics, method string, setHeader func(metadata.MD) error) error {
+	if method == grpc_health_v1.Health_Check_FullMethodName || method == grpc_health_v1.Health_Watch_FullMethodName {
+		return nil
+	}
+	conf := config.GetGRPC().RateLimit
+	var buckets []RateLimitBucket
+	var keys []string
+	for _, rule := range conf.Rules {
+		if !rateLimitRuleMatches(rule.Method, method) {
+			continue
+		}
+		value := rateLimitKeyValue(ctx, rule.Key, method, conf.TrustForwardedFor)
+		if value == "" {
+			continue
+		}
+		burst := rule.Burst
+		if burst <= 0 {
+			burst = max(1, int(math.Ceil(rule.Rate)))
+		}
+		bucket := RateLimitBucket{Key: rule.Method + "|" + rule.Key + "|" + value, Rate: rule.Rate, Burst: burst}
+		if slices.ContainsFunc(buckets, func(b RateLimitBucket) bool { return b.Key == bucket.Key }) {
+			continue
+		}
+		buckets = append(buckets, bucket)
+		keys = append(keys, rule.Key)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+	allowed, limited, retryAfter, err := limiter.Allow(ctx, buckets)
+	if err != nil {
+		for _, key := range keys {
+			metrics.observeRateLimit(method, key, "error")
+		}
+		if srvCtx, ok := core.SrvCtxFromContext(ctx); ok {
+			srvCtx.Logger().Warn("rate limiter failed, allowing request", "keys", keys, "error", err)
+		}
+		return nil
+	}
+	if !allowed {
+		metrics.observeRateLimit(method, keys[limited], "limited")
+		return rateLimitedError(retryAfter, setHeader)
+	}
+	for _, key := range keys {
+		metrics.observeRateLimit(method, key, "allowed")
+	}
+	return nil
+}
+
+func rateLimitRuleMatches(pattern, method string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(method, prefix)
+	}
+	return pattern == "" || pattern == method
+}
+
+func rateLimitKeyValue(ctx context.Context, key, method string, trustForwardedFor bool) string {
+	switch key {
+	case "method":
+		return method
+	case "user":
+		if srvCtx, ok := core.SrvCtxFromContext(ctx); ok {
+			if userInfo := srvCtx.GetUserInfo(); userInfo != nil && userInfo.GetUserID() != 0 {
+				return strconv.FormatUint(uint64(userInfo.GetUserID()), 10)
+			}
+		}
+	case "biz":
+		return callerBizName(ctx)
+	case "ip":
+		return clientIP(ctx, trustForwardedFor)
+	}
+	return ""
+}
+
+// clientIP returns the gRPC peer address, or the last x-forwarded-for entry
+// when it is trusted: the gateway appends the address of its HTTP client.
+func clientIP(ctx context.Context, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("x-forwarded-for"); len(values) > 0 {
+				entries := strings.Split(values[len(values)-1], ",")
+				if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+					return ip
+				}
+			}
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// rateLimitedError returns ResourceExhausted with a RetryInfo detail and the
+// retry-after header in whole seconds.
+func rateLimitedError(retryAfter time.Duration, setHeader func(metadata.MD) error) error {
+	seconds := max(1, int64(math.Ceil(retryAfter.Seconds())))
+	// Fails only without a gRPC transport, as in direct calls.
+	_ = setHeader(metadata.Pairs(RetryAfterHeader, strconv.FormatInt(seconds, 10)))
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// idleBucketSweep is how often LocalRateLimiter forgets full buckets.
+const idleBucketSweep = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// LocalRateLimiter keeps token buckets in process memory, so each replica
+// enforces its own limits.
+type LocalRateLimiter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewLocalRateLimiter() *LocalRateLimiter {
+	return &LocalRateLimiter{now: time.Now, buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *LocalRateLimiter) Allow(_ context.Context, buckets []RateLimitBucket) (bool, int, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= idleBucketSweep {
+		l.sweep(now)
+	}
+
+	limited, retryAfter := -1, time.Duration(0)
+	states := make([]*tokenBucket, len(buckets))
+	for i, b := range buckets {
+		bucket, ok := l.buckets[b.Key]
+		if !ok {
+			bucket = &tokenBucket{tokens: float64(b.Burst), last: now}
+			l.buckets[b.Key] = bucket
+		}
+		bucket.rate, bucket.burst = b.Rate, float64(b.Burst)
+		bucket.refill(now)
+		states[i] = bucket
+		if bucket.tokens < 1 {
+			wait := time.Duration((1 - bucket.tokens) / b.Rate * float64(time.Second))
+			if limited < 0 || wait > retryAfter {
+				limited, retryAfter = i, wait
+			}
+		}
+	}
+	if limited >= 0 {
+		return false, limited, retryAfter, nil
+	}
+	for _, bucket := range states {
+		bucket.tokens--
+	}
+	return true, -1, 0, nil
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+}
+
+// sweep drops buckets that have refilled completely; they behave the same
+// as new ones.
+func (l *LocalRateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
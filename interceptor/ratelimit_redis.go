@@ -0,0 +1,93 @@
+package interceptor
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/iconnor-code/cogo/cerrs"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript takes one token from each bucket in KEYS, or none when
+// any of them is empty; ARGV holds the rate and burst of each key in turn.
+// Time comes from the Redis server so replicas with skewed clocks share the
+// budget. Fractional values are returned as strings: Redis truncates Lua
+// numbers.
+var tokenBucketScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local tokens = {}
+local allowed = 1
+local limited = 0
+local wait = 0
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local current = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if current == nil or ts == nil then
+		current = burst
+		ts = now
+	end
+	current = math.min(burst, current + math.max(0, now - ts) * rate)
+	tokens[i] = current
+	if current < 1 then
+		local need = (1 - current) / rate
+		if allowed == 1 or need > wait then
+			limited = i
+			wait = need
+		end
+		allowed = 0
+	end
+end
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	end
+	redis.call('HSET', key, 'tokens', tostring(tokens[i]), 'ts', tostring(now))
+	redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+end
+return {allowed, limited - 1, tostring(wait)}
+`)
+
+// RedisRateLimiter keeps token buckets in Redis, so all replicas of a
+// service share one budget per key. The buckets of one request are updated by one
+// script, so they must live on one node: Redis Cluster is not supported.
+type RedisRateLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisRateLimiter stores buckets under prefix, e.g. "account:ratelimit:";
+// pass a RedisClient from client.NewRedisClient as client.
+func NewRedisRateLimiter(client redis.Scripter, prefix string) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client, prefix: prefix}
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, buckets []RateLimitBucket) (bool, int, time.Duration, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, 2*len(buckets))
+	for _, bucket := range buckets {
+		keys = append(keys, l.prefix+bucket.Key)
+		args = append(args, strconv.FormatFloat(bucket.Rate, 'f', -1, 64), bucket.Burst)
+	}
+	result, err := tokenBucketScript.Run(ctx, l.client, keys, args...).Slice()
+	if err != nil {
+		return false, 0, 0, cerrs.Wrap(err, "running rate limit script error")
+	}
+	if len(result) != 3 {
+		return false, 0, 0, cerrs.New("unexpected rate limit script result")
+	}
+	allowed, _ := result[0].(int64)
+	limited, _ := result[1].(int64)
+	wait, _ := result[2].(string)
+	seconds, err := strconv.ParseFloat(wait, 64)
+	if err != nil {
+		return false, 0, 0, cerrs.Wrap(err, "parsing rate limit script result error")
+	}
+	return allowed == 1, int(limited), time.Duration(seconds * float64(time.Second)), nil
+}
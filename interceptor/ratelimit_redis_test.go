@@ -0,0 +1,99 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisRateLimiterTokenBucket(t *testing.T) {
+	server := miniredis.RunT(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(start)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	limiter := NewRedisRateLimiter(rdb, "account:ratelimit:")
+	ctx := context.Background()
+
+	allow := func(key string) (bool, time.Duration) {
+		t.Helper()
+		allowed, _, wait, err := limiter.Allow(ctx, []RateLimitBucket{{Key: key, Rate: 2, Burst: 2}})
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		return allowed, wait
+	}
+
+	for i := range 2 {
+		if allowed, wait := allow("login"); !allowed || wait != 0 {
+			t.Fatalf("call %d = %v, %v; want allowed within the burst", i, allowed, wait)
+		}
+	}
+	if allowed, wait := allow("login"); allowed || wait != 500*time.Millisecond {
+		t.Fatalf("call over the burst = %v, %v; want denied with retry after 500ms", allowed, wait)
+	}
+	if allowed, _ := allow("search"); !allowed {
+		t.Fatal("another key shares the login bucket")
+	}
+
+	// Two tokens per second: 250ms refills half a token.
+	server.SetTime(start.Add(250 * time.Millisecond))
+	if allowed, wait := allow("login"); allowed || wait != 250*time.Millisecond {
+		t.Fatalf("call after 250ms = %v, %v; want denied with retry after 250ms", allowed, wait)
+	}
+	server.SetTime(start.Add(time.Second))
+	for i := range 2 {
+		if allowed, _ := allow("login"); !allowed {
+			t.Fatalf("call %d after a refill was denied", i)
+		}
+	}
+	if allowed, _ := allow("login"); allowed {
+		t.Fatal("refill went past the burst")
+	}
+
+	if ttl := server.TTL("account:ratelimit:login"); ttl <= 0 {
+		t.Fatalf("bucket ttl = %v, want the bucket to expire", ttl)
+	}
+}
+
+func TestRedisRateLimiterTakesTokensOnlyWhenEveryBucketAllows(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	limiter := NewRedisRateLimiter(rdb, "")
+	ctx := context.Background()
+	method := RateLimitBucket{Key: "method", Rate: 1, Burst: 2}
+	user := RateLimitBucket{Key: "user|7", Rate: 0.5, Burst: 1}
+
+	if allowed, _, _, err := limiter.Allow(ctx, []RateLimitBucket{method, user}); err != nil || !allowed {
+		t.Fatalf("first call = %v, %v; want allowed", allowed, err)
+	}
+	for i := range 3 {
+		allowed, limited, wait, err := limiter.Allow(ctx, []RateLimitBucket{method, user})
+		if err != nil || allowed || limited != 1 || wait != 2*time.Second {
+			t.Fatalf("call %d = %v, %d, %v, %v; want limited by the user bucket for 2s", i, allowed, limited, wait, err)
+		}
+	}
+	// The method bucket kept the token the rejected calls did not take.
+	if allowed, _, _, err := limiter.Allow(ctx, []RateLimitBucket{method}); err != nil || !allowed {
+		t.Fatalf("method bucket was drained by rejected calls: %v, %v", allowed, err)
+	}
+	if allowed, limited, wait, _ := limiter.Allow(ctx, []RateLimitBucket{user, method}); allowed || limited != 0 || wait != 2*time.Second {
+		t.Fatalf("call with both buckets empty = %v, %d, %v; want the user bucket, which refills last", allowed, limited, wait)
+	}
+}
+
+func TestRedisRateLimiterReportsRedisErrors(t *testing.T) {
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	server.Close()
+
+	if _, _, _, err := NewRedisRateLimiter(rdb, "").Allow(context.Background(), []RateLimitBucket{{Key: "login", Rate: 1, Burst: 1}}); err == nil {
+		t.Fatal("allow with Redis down succeeded")
+	}
+}
@@ -0,0 +1,147 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type rateLimitConfig struct{ core.GRPCRateLimitConfig }
+
+func (c rateLimitConfig) GetGRPC() core.GRPCConfig {
+	return core.GRPCConfig{RateLimit: c.GRPCRateLimitConfig}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, []RateLimitBucket) (bool, int, time.Duration, error) {
+	return false, 0, 0, errors.New("redis unavailable")
+}
+
+func TestLocalRateLimiterRefillsTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewLocalRateLimiter()
+	limiter.now = func() time.Time { return now }
+	allow := func() (bool, time.Duration) {
+		allowed, _, retryAfter, err := limiter.Allow(context.Background(), []RateLimitBucket{{Key: "user|7", Rate: 2, Burst: 2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return allowed, retryAfter
+	}
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := allow(); !allowed {
+			t.Fatalf("request %d limited within the burst", i)
+		}
+	}
+	if allowed, retryAfter := allow(); allowed || retryAfter != 500*time.Millisecond {
+		t.Fatalf("allow() = (%v, %v), want limited for 500ms", allowed, retryAfter)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if allowed, _ := allow(); !allowed {
+		t.Fatal("request limited after a token was refilled")
+	}
+
+	now = now.Add(2 * idleBucketSweep)
+	_, _, _, _ = limiter.Allow(context.Background(), []RateLimitBucket{{Key: "user|8", Rate: 2, Burst: 2}})
+	if _, ok := limiter.buckets["user|7"]; ok {
+		t.Fatal("full idle bucket was not swept")
+	}
+}
+
+func TestRateLimitInterceptorLimitsPerUser(t *testing.T) {
+	config := rateLimitConfig{core.GRPCRateLimitConfig{Rules: []core.GRPCRateLimitRule{
+		{Method: "/test.Service/*", Key: "user", Rate: 0.5, Burst: 1},
+	}}}
+	metrics, err := NewServerMetrics("test", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := RateLimitInterceptor(config, NewLocalRateLimiter(), metrics)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+	call := func(userID uint32) error {
+		serviceContext := srvctx.NewSrvCtx(&captureLogger{})
+		if userID != 0 {
+			serviceContext.SetUserInfo(&srvctx.UserInfo{UserID: userID})
+		}
+		ctx := context.WithValue(context.Background(), core.SrvCtx, serviceContext)
+		_, err := interceptor(ctx, nil, info, func(context.Context, any) (any, error) { return nil, nil })
+		return err
+	}
+
+	if err := call(7); err != nil {
+		t.Fatal(err)
+	}
+	err = call(7)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("second call err = %v, want ResourceExhausted", err)
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("details = %v, want RetryInfo", st.Details())
+	}
+	if retry, ok := st.Details()[0].(*errdetails.RetryInfo); !ok || retry.GetRetryDelay().AsDuration() <= time.Second {
+		t.Fatalf("details = %v, want a retry delay of about 2s", st.Details())
+	}
+	if err := call(8); err != nil {
+		t.Fatalf("other user limited: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := call(0); err != nil {
+			t.Fatalf("anonymous call limited by a user rule: %v", err)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.rateLimit.WithLabelValues("/test.Service/Call", "user", "limited")); got != 1 {
+		t.Fatalf("limited count = %v, want 1", got)
+	}
+
+	interceptor = RateLimitInterceptor(config, failingLimiter{}, metrics)
+	if err := call(7); err != nil {
+		t.Fatalf("limiter failure rejected the request: %v", err)
+	}
+}
+
+func TestRateLimitInterceptorTakesTokensOnlyWhenEveryRuleAllows(t *testing.T) {
+	config := rateLimitConfig{core.GRPCRateLimitConfig{Rules: []core.GRPCRateLimitRule{
+		{Method: "/test.Service/*", Key: "method", Rate: 0.001, Burst: 3},
+		{Method: "/test.Service/*", Key: "user", Rate: 0.001, Burst: 1},
+	}}}
+	interceptor := RateLimitInterceptor(config, NewLocalRateLimiter(), nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+	call := func(userID uint32) error {
+		serviceContext := srvctx.NewSrvCtx(&captureLogger{})
+		serviceContext.SetUserInfo(&srvctx.UserInfo{UserID: userID})
+		ctx := context.WithValue(context.Background(), core.SrvCtx, serviceContext)
+		_, err := interceptor(ctx, nil, info, func(context.Context, any) (any, error) { return nil, nil })
+		return err
+	}
+
+	if err := call(7); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := call(7); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("repeated call %d err = %v, want ResourceExhausted", i, err)
+		}
+	}
+	// The rejected calls left the method bucket with two tokens.
+	for _, userID := range []uint32{8, 9} {
+		if err := call(userID); err != nil {
+			t.Fatalf("user %d limited by tokens taken for rejected calls: %v", userID, err)
+		}
+	}
+	if err := call(10); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("call over the method burst err = %v, want ResourceExhausted", err)
+	}
+}
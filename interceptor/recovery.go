@@ -4,11 +4,11 @@ package interceptor
 import (
 	"context"
 	"fmt"
+	"runtime/debug"
 
 	"github.com/iconnor-code/cogo/cerrs"
 	"github.com/iconnor-code/cogo/core"
 
-	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -20,18 +20,37 @@ func RecoveryInterceptor() grpc.UnaryServerInterceptor {
 		if !ok {
 			return nil, status.Errorf(codes.Internal, "srvctx is required")
 		}
-		logger := srvCtx.Logger()
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Error("panic error",
-					zap.String("method", info.FullMethod),
-					zap.Any("error", r),
-					zap.StackSkip("stack", 1),
-				)
-				err = cerrs.Wrap(fmt.Errorf("%v", r), "panic recovered")
+				err = recoveredError(srvCtx.Logger(), r)
 				res = nil
 			}
 		}()
 		return handler(ctx, req)
 	}
 }
+
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		srvCtx, ok := core.SrvCtxFromContext(ss.Context())
+		if !ok {
+			return status.Errorf(codes.Internal, "srvctx is required")
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(srvCtx.Logger(), r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// recoveredError logs the panic through the request logger, which carries
+// the method.
+func recoveredError(logger core.ILogger, r any) error {
+	logger.Error("panic error",
+		"error", r,
+		"stack", string(debug.Stack()),
+	)
+	return cerrs.Wrap(fmt.Errorf("%v", r), "panic recovered")
+}
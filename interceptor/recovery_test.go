@@ -13,14 +13,15 @@ import (
 
 type testLogger struct{}
 
-func (l *testLogger) Log(...any) error       { return nil }
-func (l *testLogger) Debug(string, ...any)   {}
-func (l *testLogger) Info(string, ...any)    {}
-func (l *testLogger) Warn(string, ...any)    {}
-func (l *testLogger) Error(string, ...any)   {}
-func (l *testLogger) Fatal(string, ...any)   {}
-func (l *testLogger) Panic(string, ...any)   {}
-func (l *testLogger) AddGlobalFields(...any) {}
+func (l *testLogger) Log(...any) error         { return nil }
+func (l *testLogger) Debug(string, ...any)     {}
+func (l *testLogger) Info(string, ...any)      {}
+func (l *testLogger) Warn(string, ...any)      {}
+func (l *testLogger) Error(string, ...any)     {}
+func (l *testLogger) Fatal(string, ...any)     {}
+func (l *testLogger) Panic(string, ...any)     {}
+func (l *testLogger) AddGlobalFields(...any)   {}
+func (l *testLogger) With(...any) core.ILogger { return l }
 
 type testSrvCtx struct {
 	logger core.ILogger
@@ -33,6 +34,8 @@ func (s *testSrvCtx) SetBizInfo(core.IBizInfo)            {}
 func (s *testSrvCtx) GetBizInfo() core.IBizInfo           { return nil }
 func (s *testSrvCtx) SetUserInfo(core.IUserInfo)          {}
 func (s *testSrvCtx) GetUserInfo() core.IUserInfo         { return nil }
+func (s *testSrvCtx) SetPeerIdentity(core.IPeerIdentity)  {}
+func (s *testSrvCtx) GetPeerIdentity() core.IPeerIdentity { return nil }
 
 func TestRecoveryInterceptorRecoverPanic(t *testing.T) {
 	itc := RecoveryInterceptor()
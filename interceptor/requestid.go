@@ -0,0 +1,66 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxRequestIDLength bounds IDs accepted from callers, which end up in
+// every log entry of the request.
+const maxRequestIDLength = 128
+
+// RequestIDInterceptor keeps the x-request-id of the caller, or generates
+// one, and returns it in the response header and trailer. It must run
+// before SrvCtxInterceptor, which stores the ID on ISrvCtx and the request
+// logger.
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, id := withRequestID(ctx)
+		md := metadata.Pairs(core.RequestIDHeader, id)
+		// Both fail only without a gRPC transport, as in direct calls.
+		_ = grpc.SetHeader(ctx, md)
+		_ = grpc.SetTrailer(ctx, md)
+		return handler(ctx, req)
+	}
+}
+
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestID(ss.Context())
+		md := metadata.Pairs(core.RequestIDHeader, id)
+		_ = ss.SetHeader(md)
+		ss.SetTrailer(md)
+		return handler(srv, wrapServerStream(ss, ctx))
+	}
+}
+
+// withRequestID returns ctx with a valid request ID in its incoming
+// metadata, replacing a missing or malformed one with a new UUID.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(core.RequestIDHeader); len(values) > 0 && validRequestID(values[0]) {
+		return ctx, values[0]
+	}
+	id := uuid.NewString()
+	md = md.Copy()
+	md.Set(core.RequestIDHeader, id)
+	return metadata.NewIncomingContext(ctx, md), id
+}
+
+// validRequestID accepts printable ASCII without spaces, so caller IDs
+// cannot forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDInterceptorKeepsOrGeneratesID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "caller id", incoming: "gw-7f3a", keep: true},
+		{name: "missing"},
+		{name: "malformed", incoming: "forged\nline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.incoming != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(core.RequestIDHeader, tt.incoming))
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+			var got any
+			_, err := RequestIDInterceptor()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				return SrvCtxInterceptor(&captureLogger{})(ctx, req, info, func(ctx context.Context, _ any) (any, error) {
+					srvCtx, _ := core.SrvCtxFromContext(ctx)
+					got, _ = srvCtx.GetField(core.RequestIDKey)
+					return nil, nil
+				})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, _ := got.(string)
+			if tt.keep {
+				if id != tt.incoming {
+					t.Fatalf("request id = %q, want %q", id, tt.incoming)
+				}
+				return
+			}
+			if _, err := uuid.Parse(id); err != nil {
+				t.Fatalf("request id = %q, want a generated UUID", id)
+			}
+		})
+	}
+}
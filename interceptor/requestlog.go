@@ -2,63 +2,114 @@ package interceptor
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"github.com/iconnor-code/cogo/core"
 
-	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 )
 
 func RequestLogInterceptor() grpc.UnaryServerInterceptor {
+	return RequestLogInterceptorWithOptions()
+}
+
+// RequestLogInterceptorWithOptions logs like RequestLogInterceptor and, with
+// WithPayloadLog, adds the request and response payloads of the methods
+// selected by grpc.payload_log.
+func RequestLogInterceptorWithOptions(options ...RequestLogOption) grpc.UnaryServerInterceptor {
+	opts := requestLogOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		srvCtx, ok := core.SrvCtxFromContext(ctx)
 		if !ok {
 			return nil, status.Errorf(codes.Internal, "srvctx is required")
 		}
-		logger := srvCtx.Logger()
+		if info.FullMethod == grpc_health_v1.Health_Check_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		var payloadConf core.GRPCPayloadLogConfig
+		var redactKeys *regexp.Regexp
+		logPayload := false
+		if opts.payloadConfig != nil {
+			payloadConf = opts.payloadConfig.GetGRPC().PayloadLog
+			logPayload = payloadLogEnabled(payloadConf, info.FullMethod)
+		}
+		if logPayload {
+			redactKeys = opts.redactKeys.get(opts.payloadConfig.GetLogger().Redact)
+		}
+		var fields []any
+		if logPayload {
+			// Encoded before the handler runs, which may modify req.
+			if payload, ok := encodePayload(req, payloadConf, opts.maskOption, redactKeys); ok {
+				fields = append(fields, "request", payload)
+			}
+		}
 		start := time.Now()
 
 		resp, err := handler(ctx, req)
 
-		if info.FullMethod == "/grpc.health.v1.Health/Check" {
-			return resp, err
+		if logPayload && err == nil {
+			if payload, ok := encodePayload(resp, payloadConf, opts.maskOption, redactKeys); ok {
+				fields = append(fields, "response", payload)
+			}
 		}
+		logRequest(srvCtx, time.Since(start), err, fields...)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
 
-		duration := time.Since(start)
-		if err == nil {
-			logger.Info("request completed",
-				zap.String("method", info.FullMethod),
-				zap.Duration("took", duration),
-			)
-			return resp, nil
+func RequestLogStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		srvCtx, ok := core.SrvCtxFromContext(ss.Context())
+		if !ok {
+			return status.Errorf(codes.Internal, "srvctx is required")
 		}
+		start := time.Now()
 
-		if st, ok := status.FromError(err); ok {
-			fields := []any{
-				zap.String("method", info.FullMethod),
-				zap.Duration("took", duration),
-				zap.String("code", st.Code().String()),
-			}
-			switch st.Code() {
-			case codes.Canceled:
-				logger.Info("request canceled", fields...)
-			case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied,
-				codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition:
-				logger.Warn("request failed", fields...)
-			default:
-				logger.Error("request failed", fields...)
-			}
-			return nil, err
+		err := handler(srv, ss)
+
+		if info.FullMethod == grpc_health_v1.Health_Watch_FullMethodName {
+			return err
 		}
+		logRequest(srvCtx, time.Since(start), err)
+		return err
+	}
+}
+
+// logRequest logs through the request logger, which already carries the
+// method, request ID, trace, biz and user fields.
+func logRequest(srvCtx core.ISrvCtx, duration time.Duration, err error, extra ...any) {
+	logger := srvCtx.Logger()
+	fields := append([]any{"took", duration}, extra...)
+	if err == nil {
+		logger.Info("request completed", fields...)
+		return
+	}
 
-		logger.Error("request failed",
-			zap.String("method", info.FullMethod),
-			zap.Duration("took", duration),
-			zap.Error(err),
-		)
-		return nil, err
+	if st, ok := status.FromError(err); ok {
+		fields = append(fields, "code", st.Code().String())
+		switch st.Code() {
+		case codes.Canceled:
+			logger.Info("request canceled", fields...)
+		case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied,
+			codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition:
+			logger.Warn("request failed", fields...)
+		default:
+			logger.Error("request failed", fields...)
+		}
+		return
 	}
+
+	logger.Error("request failed", append(fields, "error", err)...)
 }
@@ -28,6 +28,7 @@ func (l *captureLogger) Error(message string, fields ...any) { l.add("error:"+me
 func (l *captureLogger) Fatal(string, ...any)                {}
 func (l *captureLogger) Panic(string, ...any)                {}
 func (l *captureLogger) AddGlobalFields(...any)              {}
+func (l *captureLogger) With(...any) core.ILogger            { return l }
 
 func TestCustomErrorMapping(t *testing.T) {
 	tests := []struct {
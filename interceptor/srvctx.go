@@ -2,16 +2,84 @@ package interceptor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 
 	"github.com/iconnor-code/cogo/core"
 	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 func SrvCtxInterceptor(logger core.ILogger) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		srvCtx := srvctx.NewSrvCtx(logger)
-		ctx = context.WithValue(ctx, core.SrvCtx, srvCtx)
-		return handler(ctx, req)
+		return handler(withSrvCtx(ctx, logger, info.FullMethod), req)
 	}
 }
+
+func SrvCtxStreamInterceptor(logger core.ILogger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, wrapServerStream(ss, withSrvCtx(ss.Context(), logger, info.FullMethod)))
+	}
+}
+
+// withSrvCtx stores a SrvCtx whose logger is a child of logger carrying the
+// method, request ID and trace IDs of this request, and the identity of a
+// verified mTLS client certificate.
+func withSrvCtx(ctx context.Context, logger core.ILogger, method string) context.Context {
+	fields := []any{"method", method}
+	ext := make(map[core.SrvCtxKey]string)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(core.RequestIDHeader); len(values) > 0 && values[0] != "" {
+			ext[core.RequestIDKey] = values[0]
+		}
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		ext[core.TraceIDKey] = spanCtx.TraceID().String()
+		ext[core.SpanIDKey] = spanCtx.SpanID().String()
+	}
+	for _, key := range []core.SrvCtxKey{core.RequestIDKey, core.TraceIDKey, core.SpanIDKey} {
+		if value, ok := ext[key]; ok {
+			fields = append(fields, string(key), value)
+		}
+	}
+
+	if logger != nil {
+		logger = logger.With(fields...)
+	}
+	srvCtx := srvctx.NewSrvCtx(logger)
+	for key, value := range ext {
+		srvCtx.SetField(key, value)
+	}
+	if identity := peerIdentity(ctx); identity != nil {
+		srvCtx.SetPeerIdentity(identity)
+	}
+	return context.WithValue(ctx, core.SrvCtx, srvCtx)
+}
+
+// peerIdentity returns the client certificate of the connection if the
+// server verified it against tls.ca_file.
+func peerIdentity(ctx context.Context) *srvctx.PeerIdentity {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := info.State.PeerCertificates[0]
+	fingerprint := sha256.Sum256(cert.Raw)
+	identity := &srvctx.PeerIdentity{
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity
+}
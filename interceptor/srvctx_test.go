@@ -0,0 +1,95 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type childLogger struct {
+	captureLogger
+	fields map[string]string
+}
+
+func (l *childLogger) With(fields ...any) core.ILogger {
+	child := &childLogger{fields: make(map[string]string)}
+	for key, value := range l.fields {
+		child.fields[key] = value
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		child.fields[fields[i].(string)] = fmt.Sprint(fields[i+1])
+	}
+	return child
+}
+
+func TestSrvCtxInterceptorBuildsRequestLogger(t *testing.T) {
+	root := &childLogger{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+
+	var srvCtx core.ISrvCtx
+	_, err := SrvCtxInterceptor(root)(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		srvCtx, _ = core.SrvCtxFromContext(ctx)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestID, _ := srvCtx.GetField(core.RequestIDKey); requestID != "req-1" {
+		t.Fatalf("request id field = %v, want req-1", requestID)
+	}
+	logger, ok := srvCtx.Logger().(*childLogger)
+	if !ok || logger == root {
+		t.Fatalf("srvctx logger = %T, want a child of the root logger", srvCtx.Logger())
+	}
+	if logger.fields["method"] != info.FullMethod || logger.fields["request_id"] != "req-1" {
+		t.Fatalf("request logger fields = %v, want method and request_id", logger.fields)
+	}
+	if len(root.fields) != 0 {
+		t.Fatalf("root logger was mutated: %v", root.fields)
+	}
+}
+
+func TestSrvCtxInterceptorExposesVerifiedPeerIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/prod/sa/order")
+	cert := &x509.Certificate{
+		Raw:      []byte("der"),
+		Subject:  pkix.Name{CommonName: "order.internal"},
+		DNSNames: []string{"order.internal"},
+		URIs:     []*url.URL{spiffe},
+	}
+	withPeer := func(verified bool) context.Context {
+		state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified {
+			state.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+	identity := func(ctx context.Context) core.IPeerIdentity {
+		var got core.IPeerIdentity
+		_, _ = SrvCtxInterceptor(nil)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req any) (any, error) {
+			srvCtx, _ := core.SrvCtxFromContext(ctx)
+			got = srvCtx.GetPeerIdentity()
+			return nil, nil
+		})
+		return got
+	}
+
+	got := identity(withPeer(true))
+	if got == nil || got.GetCommonName() != "order.internal" || got.GetURIs()[0] != spiffe.String() || len(got.GetFingerprint()) != 64 {
+		t.Fatalf("peer identity = %+v", got)
+	}
+	if got := identity(withPeer(false)); got != nil {
+		t.Fatalf("unverified certificate exposed as %+v", got)
+	}
+}
@@ -0,0 +1,25 @@
+package interceptor
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// serverStream overrides the context of an embedded grpc.ServerStream so
+// stream interceptors can hand an enriched context to the next handler.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func wrapServerStream(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if ctx == stream.Context() {
+		return stream
+	}
+	return &serverStream{ServerStream: stream, ctx: ctx}
+}
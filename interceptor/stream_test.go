@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iconnor-code/cogo/core"
+	cogoconfig "github.com/iconnor-code/cogo/core/impl/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func chainStream(interceptors []grpc.StreamServerInterceptor, info *grpc.StreamServerInfo, handler grpc.StreamHandler) grpc.StreamHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, itc := handler, interceptors[i]
+		handler = func(srv any, ss grpc.ServerStream) error {
+			return itc(srv, ss, info, next)
+		}
+	}
+	return handler
+}
+
+func TestStreamInterceptorsPropagateSrvCtx(t *testing.T) {
+	conf := &cogoconfig.Config{Config: core.Config{BizID: 7, BizName: "account"}}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("biz_id", "3", "biz_name", "gateway"))
+	info := &grpc.StreamServerInfo{FullMethod: "/svc/Watch", IsServerStream: true}
+
+	handler := chainStream([]grpc.StreamServerInterceptor{
+		SrvCtxStreamInterceptor(&testLogger{}),
+		ErrorStreamInterceptor(),
+		RecoveryStreamInterceptor(),
+		CycleCheckStreamInterceptor(),
+		BizInfoStreamInterceptor(conf),
+		UserInfoStreamInterceptor(conf, "/svc/Watch"),
+	}, info, func(_ any, ss grpc.ServerStream) error {
+		srvCtx, ok := core.SrvCtxFromContext(ss.Context())
+		if !ok {
+			t.Fatal("expected srvctx on stream context")
+		}
+		if got := srvCtx.GetBizInfo().GetCallerBizName(); got != "gateway" {
+			t.Fatalf("caller biz name = %q, want gateway", got)
+		}
+		md, _ := metadata.FromOutgoingContext(ss.Context())
+		if got := md.Get(CallerMethodsKey); len(got) != 1 || got[0] != "/svc/Watch" {
+			t.Fatalf("caller methods = %v", got)
+		}
+		return nil
+	})
+	if err := handler(nil, &testServerStream{ctx: ctx}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStreamInterceptorsRecoverAndMapPanic(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/svc/Watch", IsServerStream: true}
+	handler := chainStream([]grpc.StreamServerInterceptor{
+		SrvCtxStreamInterceptor(&testLogger{}),
+		ErrorStreamInterceptor(),
+		RecoveryStreamInterceptor(),
+	}, info, func(any, grpc.ServerStream) error {
+		panic("database password leaked")
+	})
+	err := handler(nil, &testServerStream{ctx: context.Background()})
+	if status.Code(err) != codes.Internal || status.Convert(err).Message() != "internal error occurred" {
+		t.Fatalf("unexpected transport error: %v", err)
+	}
+}
+
+func TestUserInfoStreamInterceptorRequiresToken(t *testing.T) {
+	conf := &cogoconfig.Config{Config: core.Config{JWT: core.JWTConfig{AccessSecret: "secret"}}}
+	info := &grpc.StreamServerInfo{FullMethod: "/svc/Watch", IsServerStream: true}
+	handler := chainStream([]grpc.StreamServerInterceptor{
+		SrvCtxStreamInterceptor(&testLogger{}),
+		UserInfoStreamInterceptor(conf),
+	}, info, func(any, grpc.ServerStream) error {
+		t.Fatal("handler should not be called")
+		return nil
+	})
+	err := handler(nil, &testServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{})})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", status.Code(err))
+	}
+}
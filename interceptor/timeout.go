@@ -0,0 +1,106 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultMinDeadlineBudget is the remaining time below which a request is
+// logged as arriving too late to do useful work.
+const defaultMinDeadlineBudget = 10 * time.Millisecond
+
+// TimeoutConfig sets the deadline of handler contexts. The caller's
+// deadline is kept when it is earlier than the server's.
+type TimeoutConfig struct {
+	// Default applies to unary methods without an entry in Methods; 0
+	// leaves them bounded only by the caller.
+	Default time.Duration
+	// Methods sets the timeout of full method names; streams only use
+	// these, since most of them are long-lived.
+	Methods map[string]time.Duration
+	// MinBudget logs requests that start with less time left; 0 uses 10ms.
+	MinBudget time.Duration
+}
+
+func (c TimeoutConfig) minBudget() time.Duration {
+	if c.MinBudget > 0 {
+		return c.MinBudget
+	}
+	return defaultMinDeadlineBudget
+}
+
+// TimeoutInterceptor puts the method's timeout on the handler context.
+// Cancellation is cooperative: the handler keeps running until it returns,
+// and only work that watches ctx, such as database, Redis and downstream
+// calls, stops at the deadline. A handler that returns after the deadline
+// has its result replaced with DeadlineExceeded. The handler is not moved
+// to another goroutine, so work that ignores ctx still counts against the
+// server's MaxConcurrentStreams.
+func TimeoutInterceptor(conf TimeoutConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		timeout, ok := conf.Methods[info.FullMethod]
+		if !ok {
+			timeout = conf.Default
+		}
+		ctx, cancel, err := withDeadline(ctx, timeout, conf.minBudget())
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+		return resp, err
+	}
+}
+
+// TimeoutStreamInterceptor is TimeoutInterceptor for streams, using only
+// the per-method timeouts.
+func TimeoutStreamInterceptor(conf TimeoutConfig) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel, err := withDeadline(ss.Context(), conf.Methods[info.FullMethod], conf.minBudget())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		err = handler(srv, wrapServerStream(ss, ctx))
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+		return err
+	}
+}
+
+// withDeadline applies timeout to ctx and rejects requests whose deadline
+// has already passed. Requests left with less than minBudget are logged:
+// their downstream calls are likely to fail.
+func withDeadline(ctx context.Context, timeout, minBudget time.Duration) (context.Context, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, cancel, nil
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		cancel()
+		return nil, nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+	if remaining < minBudget {
+		if srvCtx, ok := core.SrvCtxFromContext(ctx); ok {
+			srvCtx.Logger().Warn("deadline budget too small", "remaining", remaining)
+		}
+	}
+	return ctx, cancel, nil
+}
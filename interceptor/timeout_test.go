@@ -0,0 +1,69 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iconnor-code/cogo/core"
+	"github.com/iconnor-code/cogo/core/impl/srvctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTimeoutInterceptorBoundsHandlers(t *testing.T) {
+	conf := TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Methods: map[string]time.Duration{"/test.Service/Report": time.Minute},
+	}
+	waitForDeadline := func(ctx context.Context, _ any) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	_, err := TimeoutInterceptor(conf)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, waitForDeadline)
+	if status.Code(err) != codes.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Fatalf("err = %v after %v, want DeadlineExceeded after the default timeout", err, time.Since(start))
+	}
+
+	var remaining time.Duration
+	callerCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = TimeoutInterceptor(conf)(callerCtx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Report"}, func(ctx context.Context, _ any) (any, error) {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return nil, nil
+	})
+	if err != nil || remaining > 5*time.Second || remaining < 4*time.Second {
+		t.Fatalf("err = %v, remaining = %v; want the earlier caller deadline kept", err, remaining)
+	}
+}
+
+func TestTimeoutInterceptorRejectsExpiredAndLogsLowBudget(t *testing.T) {
+	logger := &captureLogger{}
+	ctx := context.WithValue(context.Background(), core.SrvCtx, srvctx.NewSrvCtx(logger))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	called := false
+	_, err := TimeoutInterceptor(TimeoutConfig{})(expired, nil, info, func(context.Context, any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	if called || status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("called = %v, err = %v; want DeadlineExceeded without running the handler", called, err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	_, _ = TimeoutInterceptor(TimeoutConfig{MinBudget: time.Second})(short, nil, info, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	if len(logger.entries) != 1 || !strings.HasPrefix(logger.entries[0], "warn:deadline budget too small") {
+		t.Fatalf("log entries = %v, want a low budget warning", logger.entries)
+	}
+}
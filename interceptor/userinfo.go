@@ -46,67 +46,91 @@ func UserInfoInterceptorWithOptions(config UserInfoConfig, whiteList []string, o
 	}
 
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		srvCtx, ok := core.SrvCtxFromContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Internal, "srvctx is required")
+		if err := authenticate(ctx, config, whiteList, opts, info.FullMethod); err != nil {
+			return nil, err
 		}
+		return handler(ctx, req)
+	}
+}
 
-		// skip white list
-		if slices.Contains(whiteList, info.FullMethod) {
-			return handler(ctx, req)
-		}
+func UserInfoStreamInterceptor(config UserInfoConfig, whiteList ...string) grpc.StreamServerInterceptor {
+	return UserInfoStreamInterceptorWithOptions(config, whiteList)
+}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Unauthenticated, "authorization bearer token is required")
-		}
+func UserInfoStreamInterceptorWithOptions(config UserInfoConfig, whiteList []string, options ...UserInfoOption) grpc.StreamServerInterceptor {
+	opts := userInfoOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
 
-		authorizations := md.Get(token.AuthorizationHeader)
-		if len(authorizations) == 0 || len(authorizations[0]) == 0 {
-			return nil, status.Errorf(codes.Unauthenticated, "authorization bearer token is required")
-		}
-		accessToken, err := token.ExtractBearerToken(authorizations[0])
-		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "authorization bearer token is invalid")
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), config, whiteList, opts, info.FullMethod); err != nil {
+			return err
 		}
+		return handler(srv, ss)
+	}
+}
 
-		jwtToken := token.NewJwtToken(config)
-		userInfo, err := jwtToken.ParseToken(accessToken)
-		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "access token is invalid or expired")
-		}
-		tokenID, err := token.ClaimsString(userInfo, token.ClaimID)
-		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "access token id is invalid")
-		}
-		if opts.revocationChecker != nil {
-			revoked, err := opts.revocationChecker.IsTokenRevoked(ctx, tokenID)
-			if err != nil {
-				return nil, status.Errorf(codes.Internal, "check access token revocation failed")
-			}
-			if revoked {
-				return nil, status.Errorf(codes.Unauthenticated, "access token is revoked")
-			}
-		}
+func authenticate(ctx context.Context, config UserInfoConfig, whiteList []string, opts userInfoOptions, method string) error {
+	srvCtx, ok := core.SrvCtxFromContext(ctx)
+	if !ok {
+		return status.Errorf(codes.Internal, "srvctx is required")
+	}
+
+	// skip white list
+	if slices.Contains(whiteList, method) {
+		return nil
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "authorization bearer token is required")
+	}
+
+	authorizations := md.Get(token.AuthorizationHeader)
+	if len(authorizations) == 0 || len(authorizations[0]) == 0 {
+		return status.Errorf(codes.Unauthenticated, "authorization bearer token is required")
+	}
+	accessToken, err := token.ExtractBearerToken(authorizations[0])
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "authorization bearer token is invalid")
+	}
 
-		userID, err := toUint32(userInfo["user_id"])
+	jwtToken := token.NewJwtToken(config)
+	userInfo, err := jwtToken.ParseToken(accessToken)
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "access token is invalid or expired")
+	}
+	tokenID, err := token.ClaimsString(userInfo, token.ClaimID)
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "access token id is invalid")
+	}
+	if opts.revocationChecker != nil {
+		revoked, err := opts.revocationChecker.IsTokenRevoked(ctx, tokenID)
 		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "access token user_id is invalid")
+			return status.Errorf(codes.Internal, "check access token revocation failed")
 		}
-		userEmail, ok := userInfo["user_email"].(string)
-		if !ok || userEmail == "" {
-			return nil, status.Errorf(codes.InvalidArgument, "access token user_email is invalid")
+		if revoked {
+			return status.Errorf(codes.Unauthenticated, "access token is revoked")
 		}
-		isAdmin, _ := userInfo["is_admin"].(bool)
-
-		srvCtx.SetUserInfo(&srvctx.UserInfo{
-			UserID:    userID,
-			UserEmail: userEmail,
-			IsAdmin:   isAdmin,
-		})
+	}
 
-		return handler(ctx, req)
+	userID, err := toUint32(userInfo["user_id"])
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "access token user_id is invalid")
+	}
+	userEmail, ok := userInfo["user_email"].(string)
+	if !ok || userEmail == "" {
+		return status.Errorf(codes.InvalidArgument, "access token user_email is invalid")
 	}
+	isAdmin, _ := userInfo["is_admin"].(bool)
+
+	srvCtx.SetUserInfo(&srvctx.UserInfo{
+		UserID:    userID,
+		UserEmail: userEmail,
+		IsAdmin:   isAdmin,
+	})
+	return nil
 }
 
 func toUint32(v any) (uint32, error) {
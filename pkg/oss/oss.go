@@ -288,7 +288,7 @@ func (c *Client) expireDuration(value time.Duration) time.Duration {
 		return value
 	}
 	if c.conf.PresignExpire > 0 {
-		return time.Duration(c.conf.PresignExpire) * time.Second
+		return c.conf.PresignExpire.Duration()
 	}
 	return defaultPresignExpire
 }
@@ -11,8 +11,6 @@ import (
 	"time"
 
 	"github.com/iconnor-code/cogo/core"
-
-	"go.uber.org/zap"
 )
 
 type EmailSMTP struct {
@@ -63,75 +61,78 @@ func (e *EmailSMTP) sendEmail(from string, to []string, subject string, msg []by
 		InsecureSkipVerify: false,
 	}
 
+	logger := e.sendLogger(from, to, subject, msg)
 	conn, err := tls.Dial("tcp", addr, tlsConfig)
-	e.log(from, to, subject, msg)
 
 	if err != nil {
-		e.logger.Error("SMTP建立TLS连接失败", zap.Error(err))
+		logger.Error("SMTP建立TLS连接失败", "error", err)
 		return
 	}
 	defer conn.Close()
 
 	client, err := smtp.NewClient(conn, e.host)
 	if err != nil {
-		e.logger.Error("SMTP创建客户端失败", zap.Error(err))
+		logger.Error("SMTP创建客户端失败", "error", err)
 		return
 	}
 	defer client.Close()
 
 	auth := smtp.PlainAuth("", e.username, e.password, e.host)
 	if err = client.Auth(auth); err != nil {
-		e.logger.Error("SMTP认证失败", zap.Error(err))
+		logger.Error("SMTP认证失败", "error", err)
 		return
 	}
 
 	if err = client.Mail(e.username); err != nil {
-		e.logger.Error("SMTP设置发件人失败", zap.Error(err))
+		logger.Error("SMTP设置发件人失败", "error", err)
 		return
 	}
 
 	for _, rcptAddr := range to {
 		if err = client.Rcpt(rcptAddr); err != nil {
-			e.logger.Error("SMTP设置收件人失败", zap.Error(err))
+			logger.Error("SMTP设置收件人失败", "error", err)
 			return
 		}
 	}
 
 	w, err := client.Data()
 	if err != nil {
-		e.logger.Error("SMTP创建邮件内容写入器失败", zap.Error(err))
+		logger.Error("SMTP创建邮件内容写入器失败", "error", err)
 		return
 	}
 
 	_, err = w.Write(msg)
 	if err != nil {
-		e.logger.Error("SMTP写入邮件内容失败", zap.Error(err))
+		logger.Error("SMTP写入邮件内容失败", "error", err)
 		return
 	}
 
 	err = w.Close()
 	if err != nil {
-		e.logger.Error("SMTP关闭邮件内容写入器失败", zap.Error(err))
+		logger.Error("SMTP关闭邮件内容写入器失败", "error", err)
 		return
 	}
 
 	err = client.Quit()
 	if err != nil {
-		e.logger.Error("SMTP关闭连接失败", zap.Error(err))
+		logger.Error("SMTP关闭连接失败", "error", err)
 		return
 	}
 
-	e.logger.Info("SMTP发送邮件完成")
+	logger.Info("SMTP发送邮件完成")
 }
 
-func (e *EmailSMTP) log(appName string, to []string, subject string, msg []byte) {
-	e.logger.AddGlobalFields(
-		zap.String("app_name", appName),
-		zap.String("host", e.host),
-		zap.Int("port", e.port),
-		zap.String("from", e.username),
-		zap.String("to", strings.Join(to, ",")),
-		zap.String("subject", subject),
-		zap.String("msg", string(msg)),
+// sendLogger returns a child logger for one send, so concurrent sends do not
+// share recipients through e.logger. Only the size of msg is logged: bodies
+// carry verification codes and reset links.
+func (e *EmailSMTP) sendLogger(appName string, to []string, subject string, msg []byte) core.ILogger {
+	return e.logger.With(
+		"app_name", appName,
+		"host", e.host,
+		"port", e.port,
+		"from", e.username,
+		"to", strings.Join(to, ","),
+		"subject", subject,
+		"msg_size", len(msg),
 	)
 }
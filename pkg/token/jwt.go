@@ -64,8 +64,8 @@ func (j *JwtToken) GenerateToken(userInfo map[string]any) error {
 	j.AccessToken = accessToken
 	j.AccessTokenID, _ = ClaimsString(accessClaims, ClaimID)
 	j.RefreshToken = refreshToken
-	j.AccessExpireTime = time.Now().Add(time.Duration(accessExpire) * time.Hour)
-	j.RefreshExpireTime = time.Now().Add(time.Duration(refreshExpire) * time.Hour * 24)
+	j.AccessExpireTime = time.Now().Add(accessExpire.Duration())
+	j.RefreshExpireTime = time.Now().Add(refreshExpire.Duration())
 
 	return nil
 }
@@ -95,7 +95,7 @@ func (j *JwtToken) generateAccessToken(userInfo map[string]any) (string, jwt.Map
 
 	claims := jwt.MapClaims{}
 	maps.Copy(claims, userInfo)
-	claims[ClaimExpiresAt] = time.Now().Add(time.Duration(accessExpire) * time.Hour).Unix()
+	claims[ClaimExpiresAt] = time.Now().Add(accessExpire.Duration()).Unix()
 	claims[ClaimID] = uuid.NewString()
 
 	t.Claims = claims
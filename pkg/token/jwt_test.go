@@ -69,8 +69,8 @@ func TestExtractBearerToken(t *testing.T) {
 func TestGenerateTokenAddsTokenIDAndExpiration(t *testing.T) {
 	j := NewJwtToken(&cogoconfig.Config{Config: core.Config{JWT: core.JWTConfig{
 		AccessSecret:  "secret",
-		AccessExpire:  1,
-		RefreshExpire: 1,
+		AccessExpire:  core.Duration(time.Hour),
+		RefreshExpire: core.Duration(24 * time.Hour),
 	}}})
 
 	if err := j.GenerateToken(map[string]any{"user_id": uint32(123)}); err != nil {
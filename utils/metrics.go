@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterCollector registers collector with registerer and returns the
+// already registered collector when an identical one exists, so components
+// built more than once in a process share their metric series.
+func RegisterCollector[T prometheus.Collector](registerer prometheus.Registerer, collector T) (T, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if err := registerer.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return collector, err
+	}
+	return collector, nil
+}